@@ -42,10 +42,10 @@ export function SignupForm() {
       // In a real app, this would call your API
       await signup(values.name, values.email, values.password)
       toast({
-        title: "Account created",
-        description: "Welcome to GitHost!",
+        title: "Check your email",
+        description: "We sent you a link to verify your address. You can log in now.",
       })
-      router.push("/dashboard")
+      router.push("/login")
     } catch (error) {
       toast({
         variant: "destructive",
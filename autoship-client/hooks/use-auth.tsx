@@ -53,13 +53,9 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       body: JSON.stringify({ name, email, password }),
     })
 
+    // The server answers the same for new and existing emails and does not
+    // sign the user in; they log in once the account is created.
     if (!res.ok) throw new Error("Signup failed")
-
-    const data = await res.json()
-    setToken(data.token)
-    setUser(data.user)
-    localStorage.setItem("token", data.token)
-    localStorage.setItem("user", JSON.stringify(data.user))
   }
 
   const login = async (email: string, password: string) => {
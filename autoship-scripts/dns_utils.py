@@ -43,3 +43,26 @@ def add_dns_record(subdomain, ip_address):
     except Exception as e:
         logging.error(f"[Cloudflare] Exception adding DNS: {e}")
         return False
+
+
+def delete_dns_record(subdomain):
+    """Delete every A record for subdomain. No record left counts as success."""
+    try:
+        headers = {
+            "Authorization": f"Bearer {CLOUDFLARE_API_TOKEN}",
+            "Content-Type": "application/json"
+        }
+        existing = requests.get(CF_API_BASE, headers=headers, params={"type": "A", "name": subdomain}).json()
+        ok = True
+        for rec in existing.get("result", []):
+            del_resp = requests.delete(f"{CF_API_BASE}/{rec['id']}", headers=headers)
+            if del_resp.status_code not in (200, 204, 404):
+                logging.error(f"Cloudflare DNS delete failed: {del_resp.status_code}, {del_resp.text}")
+                ok = False
+        if ok:
+            logging.info(f"Cloudflare DNS records removed for {subdomain}")
+        return ok
+
+    except Exception as e:
+        logging.error(f"[Cloudflare] Exception deleting DNS: {e}")
+        return False
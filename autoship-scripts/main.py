@@ -8,14 +8,17 @@ from pathlib import Path
 from watchdog.observers import Observer
 from watchdog.events import FileSystemEventHandler
 import os
-from nginx_utils import write_nginx_conf_static, write_nginx_conf_dynamic, reload_nginx
-from ssl_utils import generate_ssl
-from dns_utils import add_dns_record
+from nginx_utils import write_nginx_conf_static, write_nginx_conf_dynamic, remove_nginx_conf, reload_nginx
+from ssl_utils import generate_ssl, delete_ssl
+from dns_utils import add_dns_record, delete_dns_record
 from response_utils import write_response
 
 # Constants
 # Path on host shared with server container
 DEPLOY_FILE = "/var/lib/autoship/deploy/deploy-requests.json"
+# Touched periodically so the server's /readyz can tell the agent is alive
+HEARTBEAT_FILE = "/var/lib/autoship/deploy/agent-heartbeat"
+HEARTBEAT_INTERVAL = 15
 
 # Ensure deploy file exists (an empty list) so reader won't fail
 Path(os.path.dirname(DEPLOY_FILE)).mkdir(parents=True, exist_ok=True)
@@ -39,7 +42,45 @@ def hash_file_content(path):
         logging.warning(f"Hashing failed: {e}")
         return None
 
+def handle_delete(req):
+    """Undo a deployment: nginx site, DNS record and certificate. Safe to repeat."""
+    req_id = req.get("id")
+    subdomain = req.get("subdomain")
+    try:
+        if not req_id or not subdomain:
+            raise ValueError("Missing required fields")
+        failed = []
+        if not remove_nginx_conf(subdomain) or not reload_nginx():
+            failed.append("nginx")
+        if not delete_dns_record(subdomain):
+            failed.append("dns")
+        if not delete_ssl(subdomain):
+            failed.append("ssl")
+        if failed:
+            raise RuntimeError(f"Removal failed for: {', '.join(failed)}")
+
+        write_response({
+            "id": req_id,
+            "subdomain": subdomain,
+            "status": "success",
+            "message": "Deleted",
+        })
+
+    except Exception as e:
+        logging.error(f"[{req_id}] Delete failed: {e}")
+        write_response({
+            "id": req_id,
+            "subdomain": subdomain,
+            "status": "error",
+            "message": str(e),
+            "error": str(e),
+        })
+
+
 def handle_request(req):
+    if req.get("action") == "delete":
+        return handle_delete(req)
+
     print("Welcome to the host handler! inside handle_request that is inside main.py")
     try:
         req_id = req.get("id") or req.get("request_id")
@@ -67,7 +108,9 @@ def handle_request(req):
                         port = None
             if not isinstance(port, int):
                 raise ValueError("Invalid or missing port for dynamic project")
-            write_nginx_conf_dynamic(subdomain, port)
+            # host is the address the container port is published on
+            # (set in private network mode)
+            write_nginx_conf_dynamic(subdomain, port, req.get("host") or "localhost")
 
         else:
             raise ValueError("Invalid project_type")
@@ -142,13 +185,24 @@ class DeployHandler(FileSystemEventHandler):
             handle_request(req)
         logging.info("Requests processed successfully.")
 
+def write_heartbeat():
+    try:
+        with open(HEARTBEAT_FILE, "w") as f:
+            f.write(str(int(time.time())))
+    except Exception as e:
+        logging.warning(f"Heartbeat write failed: {e}")
+
 def main():
     logging.info("Watching for deployment requests...")
     observer = Observer()
     observer.schedule(DeployHandler(), path=str(Path(DEPLOY_FILE).parent), recursive=False)
     observer.start()
+    last_heartbeat = 0
     try:
         while True:
+            if time.time() - last_heartbeat >= HEARTBEAT_INTERVAL:
+                write_heartbeat()
+                last_heartbeat = time.time()
             time.sleep(1)
     except KeyboardInterrupt:
         logging.info("Stopping observer.")
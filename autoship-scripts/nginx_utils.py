@@ -1,6 +1,6 @@
 import os
 import subprocess
-from config import NGINX_SITES_DIR
+from config import NGINX_SITES_DIR, NGINX_SITES_ENABLED
 
 NGINX_SITES_DIR = NGINX_SITES_DIR
 print(f"NGINX_SITES_DIR: {NGINX_SITES_DIR}")
@@ -46,7 +46,7 @@ server {{
     ssl_certificate_key /etc/letsencrypt/live/{subdomain}/privkey.pem;
 
     location / {{
-        proxy_pass http://localhost:{port};
+        proxy_pass http://{host}:{port};
         proxy_http_version 1.1;
         proxy_set_header Upgrade $http_upgrade;
         proxy_set_header Connection 'upgrade';
@@ -61,8 +61,8 @@ def write_nginx_conf_static(subdomain, s3_url):
     print(f"Writing NGINX conf for static site: {subdomain} with S3 URL: {s3_url}")
     return _write_and_reload(subdomain, conf)
 
-def write_nginx_conf_dynamic(subdomain, port):
-    conf = DYNAMIC_TEMPLATE.format(subdomain=subdomain, port=port)
+def write_nginx_conf_dynamic(subdomain, port, host="localhost"):
+    conf = DYNAMIC_TEMPLATE.format(subdomain=subdomain, host=host, port=port)
     return _write_and_reload(subdomain, conf)
 
 def _write_and_reload(subdomain, conf_content):
@@ -77,6 +77,21 @@ def _write_and_reload(subdomain, conf_content):
         print(f"[ERROR] Failed to write NGINX conf: {e}")
         return False
 
+def remove_nginx_conf(subdomain):
+    """Remove the site's config (and its sites-enabled link). Missing files are fine."""
+    try:
+        paths = [os.path.join(NGINX_SITES_DIR, f"{subdomain}.conf")]
+        if NGINX_SITES_ENABLED:
+            paths.append(os.path.join(NGINX_SITES_ENABLED, f"{subdomain}.conf"))
+        for path in paths:
+            if os.path.lexists(path):
+                os.remove(path)
+                print(f"[INFO] NGINX conf removed: {path}")
+        return True
+    except Exception as e:
+        print(f"[ERROR] Failed to remove NGINX conf: {e}")
+        return False
+
 def reload_nginx():
     try:
         subprocess.run(["sudo", "nginx", "-t"], check=True)
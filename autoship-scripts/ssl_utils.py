@@ -129,3 +129,24 @@ def generate_ssl(subdomain, retries=3, delay=20, prefer_webroot_on_failure=True)
             return False
 
     return False
+
+
+def delete_ssl(subdomain):
+    """Delete the certificate for subdomain so certbot stops renewing it."""
+    cmd = [
+        "certbot", "delete",
+        "--cert-name", subdomain,
+        "--non-interactive",
+        "--config-dir", CERTBOT_DIRS["config"],
+        "--work-dir", CERTBOT_DIRS["work"],
+        "--logs-dir", CERTBOT_DIRS["logs"],
+    ]
+    try:
+        _run_cmd(cmd)
+        logging.info(f"Certificate deleted for {subdomain}")
+        return True
+    except subprocess.CalledProcessError as e:
+        if "No certificate found" in (e.stdout or "") + (e.stderr or ""):
+            return True
+        logging.error(f"Certbot delete failed for {subdomain}: {e.stderr}")
+        return False
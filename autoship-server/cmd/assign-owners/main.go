@@ -0,0 +1,76 @@
+// Command assign-owners gives projects saved before projects recorded their
+// owner to an Auto-Ship user, so they become reachable through the API again.
+// Only the repository owner (GitHub login) was stored on those projects, so
+// the new owner has to be named explicitly:
+//
+//	go run ./cmd/assign-owners -email alice@example.com -repo-owner alice -dry-run
+//
+// Without -repo-owner every ownerless project is assigned. It reads MONGO_URI
+// like the server.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user to own the projects (required)")
+	repoOwner := flag.String("repo-owner", "", "only assign projects of this repository owner")
+	dryRun := flag.Bool("dry-run", false, "only list the projects that would be assigned")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	logger.Init(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+
+	if *email == "" {
+		logger.Fatal("-email is required", nil)
+	}
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		logger.Fatal("MONGO_URI is not set", nil)
+	}
+	db.SetMongoURI(mongoURI)
+	if err := db.Connect(); err != nil {
+		logger.Fatal("Failed to connect to MongoDB", err)
+	}
+	defer db.Disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	user, err := db.GetUserByEmail(ctx, strings.ToLower(strings.TrimSpace(*email)))
+	if err != nil {
+		logger.Fatal("No user with that email", err)
+	}
+	projects, err := db.ListOwnerlessProjects(ctx, *repoOwner)
+	if err != nil {
+		logger.Fatal("Failed to list ownerless projects", err)
+	}
+
+	assigned := 0
+	for _, p := range projects {
+		if *dryRun {
+			slog.Info("would assign project", "project_id", p.ID.Hex(), "repo_url", p.RepoURL)
+			continue
+		}
+		ok, err := db.SetProjectOwner(ctx, p.ID, user.ID)
+		if err != nil {
+			logger.Fatal("Failed to assign project", err)
+		}
+		if ok {
+			slog.Info("assigned project", "project_id", p.ID.Hex(), "repo_url", p.RepoURL)
+			assigned++
+		}
+	}
+
+	slog.Info("owners assigned", "user_id", user.ID.Hex(), "ownerless", len(projects), "assigned", assigned, "dry_run", *dryRun)
+}
@@ -0,0 +1,93 @@
+// Command reconcile-firewall closes the cloud firewall rules Auto-Ship opened
+// for ports that no project and no port reservation uses any more, e.g. rules
+// left behind by deployments deleted before ports were revoked on teardown.
+// With CONTAINER_NETWORK_MODE=private no app port needs a rule, so every rule
+// Auto-Ship opened is closed.
+//
+// It reads the same environment as the server (CLOUD_PROVIDER and its
+// settings, MONGO_URI, CONTAINER_NETWORK_MODE):
+//
+//	go run ./cmd/reconcile-firewall -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ports"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only list the rules that would be revoked")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	logger.Init(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+
+	if err := cloud.Init(os.Getenv("CLOUD_PROVIDER")); err != nil {
+		logger.Fatal("Failed to initialize cloud provider", err)
+	}
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		logger.Fatal("MONGO_URI is not set", nil)
+	}
+	db.SetMongoURI(mongoURI)
+	if err := db.Connect(); err != nil {
+		logger.Fatal("Failed to connect to MongoDB", err)
+	}
+	defer db.Disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	inUse := map[int]bool{}
+	if os.Getenv("CONTAINER_NETWORK_MODE") != services.NetworkPrivate {
+		projectPorts, err := db.ListProjectHostPorts(ctx)
+		if err != nil {
+			logger.Fatal("Failed to list project ports", err)
+		}
+		reserved, err := ports.Leased(ctx)
+		if err != nil {
+			logger.Fatal("Failed to list port leases", err)
+		}
+		for _, p := range append(projectPorts, reserved...) {
+			inUse[p] = true
+		}
+	}
+
+	open, err := cloud.Get().AuthorizedPorts(ctx)
+	if err != nil {
+		logger.Fatal("Failed to list firewall rules", err)
+	}
+
+	revoked, failed := 0, 0
+	for _, port := range open {
+		if inUse[port] {
+			continue
+		}
+		if *dryRun {
+			slog.Info("would revoke stale firewall rule", "port", port)
+			continue
+		}
+		if err := cloud.Get().RevokePort(ctx, port); err != nil {
+			slog.Error("failed to revoke firewall rule", "port", port, "error", err)
+			failed++
+			continue
+		}
+		slog.Info("revoked stale firewall rule", "port", port)
+		revoked++
+	}
+
+	slog.Info("firewall reconciled", "provider", cloud.Get().Name(), "open", len(open), "in_use", len(inUse), "revoked", revoked, "failed", failed, "dry_run", *dryRun)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,12 +1,24 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/api"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/gitprovider"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/mailer"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ports"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/quota"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ratelimit"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/services"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/sso"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/tracing"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -14,34 +26,108 @@ import (
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	envErr := godotenv.Load()
+	logger.Init(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if envErr != nil {
+		slog.Info("No .env file found, using environment variables")
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	if err := cloud.Init(os.Getenv("CLOUD_PROVIDER")); err != nil {
-		log.Fatalf("Failed to initialize cloud provider: %v", err)
+		logger.Fatal("Failed to initialize cloud provider", err)
 	}
 	if err := utils.LoadEnv(); err != nil {
-		log.Fatalf("Error loading JWT environment variables: %v", err)
+		logger.Fatal("Error loading JWT environment variables", err)
+	}
+	if err := gitprovider.Init(); err != nil {
+		logger.Fatal("Failed to initialize Git providers", err)
+	}
+	if err := sso.Init(); err != nil {
+		logger.Fatal("Failed to configure SSO", err)
+	}
+	if err := mailer.Init(os.Getenv("MAILER")); err != nil {
+		logger.Fatal("Failed to initialize mailer", err)
+	}
+	if err := ratelimit.Init(); err != nil {
+		logger.Fatal("Invalid rate limit configuration", err)
+	}
+	if err := quota.Init(); err != nil {
+		logger.Fatal("Invalid quota configuration", err)
+	}
+	if err := ports.Init(); err != nil {
+		logger.Fatal("Invalid port range", err)
+	}
+	if err := services.InitNetwork(context.Background()); err != nil {
+		logger.Fatal("Failed to set up container network", err)
 	}
 
 	mongoURI := os.Getenv("MONGO_URI")
 	if mongoURI == "" {
-		log.Fatal("MONGO_URI is not set")
+		logger.Fatal("MONGO_URI is not set", nil)
 	}
 	db.SetMongoURI(mongoURI)
-	db.Connect()
+	if err := db.Connect(); err != nil {
+		logger.Fatal("Failed to connect to MongoDB", err)
+	}
 	defer db.Disconnect()
+	if err := db.EnsureIndexes(); err != nil {
+		logger.Fatal("Failed to create MongoDB indexes", err)
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "3000"
 	}
 
-	app := fiber.New()
-	app.Use(cors.New())
+	ports.StartSweeper(services.ListContainerNames)
+
+	metrics.RegisterPortPool(ports.Counts)
+	metrics.RegisterRunningContainers(services.CountRunningContainers)
+
+	app := fiber.New(proxyConfig())
+	app.Use(logger.Middleware)
+	app.Use(tracing.Middleware)
+	app.Use(metrics.Middleware)
+	app.Use(cors.New(cors.Config{ExposeHeaders: logger.RequestIDHeader}))
 	api.RegisterRoutes(app)
+	if dir := cloud.LocalSitesDir(); dir != "" {
+		app.Static(cloud.LocalSitesPath, dir)
+	}
+
+	slog.Info("🚀 Server running", "url", "http://localhost:"+port)
+	if err := app.Listen(":" + port); err != nil {
+		logger.Fatal("Server stopped", err)
+	}
+}
 
-	log.Printf("🚀 Server running on http://localhost:%s", port)
-	log.Fatal(app.Listen(":" + port))
+// proxyConfig makes c.IP() (used for rate limiting) return the client address
+// when the server runs behind a reverse proxy. TRUSTED_PROXIES lists the
+// proxy IPs or CIDRs; only their PROXY_HEADER (default X-Real-IP, which the
+// bundled nginx config sets) is believed.
+func proxyConfig() fiber.Config {
+	proxies := os.Getenv("TRUSTED_PROXIES")
+	if proxies == "" {
+		return fiber.Config{}
+	}
+	header := os.Getenv("PROXY_HEADER")
+	if header == "" {
+		header = "X-Real-IP"
+	}
+	var trusted []string
+	for _, p := range strings.Split(proxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			trusted = append(trusted, p)
+		}
+	}
+	return fiber.Config{
+		ProxyHeader:             header,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trusted,
+		EnableIPValidation:      true,
+	}
 }
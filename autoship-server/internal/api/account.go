@@ -0,0 +1,226 @@
+// internal/api/account.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/mailer"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// verifyEmailExpiration is how long an email verification link works.
+	verifyEmailExpiration = 24 * time.Hour
+	// passwordResetExpiration is how long a password reset link works.
+	passwordResetExpiration = time.Hour
+	// mailTimeout bounds a background email delivery.
+	mailTimeout = 30 * time.Second
+)
+
+// sendMail delivers msg in the background so that slow relays do not hold
+// up the request and response times do not reveal whether an address is
+// registered. Failures are logged.
+func sendMail(c *fiber.Ctx, msg mailer.Message) {
+	log := logger.FromCtx(c)
+	ctx := logger.NewContext(context.WithoutCancel(c.UserContext()), log)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, mailTimeout)
+		defer cancel()
+		if err := mailer.Get().Send(ctx, msg); err != nil {
+			log.Error("failed to send email", "subject", msg.Subject, "error", err)
+		}
+	}()
+}
+
+// issueEmailToken replaces the user's outstanding tokens for purpose with a
+// new one and returns its value.
+func issueEmailToken(ctx context.Context, user *models.User, purpose string, ttl time.Duration) (string, error) {
+	if err := db.DeleteEmailTokens(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+	raw, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if err := db.SaveEmailToken(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// sendVerificationEmail mails user a link to FRONTEND_URL/verify-email.
+func sendVerificationEmail(c *fiber.Ctx, user *models.User) error {
+	token, err := issueEmailToken(c.UserContext(), user, models.EmailTokenVerify, verifyEmailExpiration)
+	if err != nil {
+		return err
+	}
+	link := utils.FrontendURL() + "/verify-email?token=" + url.QueryEscape(token)
+	sendMail(c, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Auto-Ship email address",
+		Body: fmt.Sprintf("Confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in 24 hours. If you did not create an Auto-Ship account, ignore this email.\n", link),
+	})
+	return nil
+}
+
+// sendSignupNotice tells the owner of an existing account that someone tried
+// to sign up with their address.
+func sendSignupNotice(c *fiber.Ctx, user *models.User) {
+	sendMail(c, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Auto-Ship account",
+		Body: fmt.Sprintf("Someone tried to create an Auto-Ship account with this email address, which already has one.\n\n"+
+			"If it was you, sign in here instead (the login page can also reset a forgotten password):\n\n%s\n\n"+
+			"If it wasn't, you can ignore this email.\n", utils.FrontendURL()+"/login"),
+	})
+}
+
+// VerifyEmail redeems a verification token from the emailed link.
+func VerifyEmail(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "token is required"})
+	}
+
+	token, err := db.ConsumeEmailToken(c.UserContext(), models.EmailTokenVerify, utils.HashToken(req.Token))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification link"})
+	}
+	if err := db.MarkEmailVerified(c.UserContext(), token.UserID, token.Email); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+
+	logger.FromCtx(c).Info("email verified", "user_id", token.UserID.Hex())
+	return c.JSON(fiber.Map{"message": "Email verified"})
+}
+
+// ResendVerification mails the signed-in user a new verification link.
+func ResendVerification(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	user, err := db.GetUserByID(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	if user.EmailVerified {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Email is already verified"})
+	}
+	if err := sendVerificationEmail(c, user); err != nil {
+		logger.FromCtx(c).Error("failed to issue verification token", "error", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send verification email"})
+	}
+	return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "Verification email sent"})
+}
+
+// ForgotPassword mails a password reset link if the email belongs to a user.
+// The response is the same either way so it cannot be used to probe for
+// accounts.
+func ForgotPassword(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	email := normalizeEmail(req.Email)
+	if email == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "A valid email is required"})
+	}
+	accepted := func() error {
+		return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "If an account exists for this email, a reset link has been sent"})
+	}
+
+	user, err := db.GetUserByEmail(c.UserContext(), email)
+	if err != nil {
+		return accepted()
+	}
+	token, err := issueEmailToken(c.UserContext(), user, models.EmailTokenPasswordReset, passwordResetExpiration)
+	if err != nil {
+		logger.FromCtx(c).Error("failed to issue password reset token", "error", err)
+		return accepted()
+	}
+
+	link := utils.FrontendURL() + "/reset-password?token=" + url.QueryEscape(token)
+	sendMail(c, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Auto-Ship password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Auto-Ship account. Choose a new one here:\n\n%s\n\n"+
+			"The link expires in 1 hour and works once. If this wasn't you, ignore this email; your password is unchanged.\n", link),
+	})
+	logger.FromCtx(c).Info("password reset requested", "user_id", user.ID.Hex())
+	return accepted()
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. Every
+// existing session of the user is revoked.
+func ResetPassword(c *fiber.Ctx) error {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "token and password are required"})
+	}
+	ctx := c.UserContext()
+
+	// The password is validated against the token's email before the token
+	// is consumed, so a rejected password does not burn the link.
+	tokenHash := utils.HashToken(req.Token)
+	pending, err := db.GetEmailToken(ctx, models.EmailTokenPasswordReset, tokenHash)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset link"})
+	}
+	if err := utils.ValidatePassword(req.Password, pending.Email); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	token, err := db.ConsumeEmailToken(ctx, models.EmailTokenPasswordReset, tokenHash)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset link"})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
+	}
+	if err := db.UpdatePassword(ctx, token.UserID, string(hash)); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	log := logger.With(c, "user_id", token.UserID.Hex())
+	// The reset link reached the inbox, which is as good as a verification.
+	if err := db.MarkEmailVerified(ctx, token.UserID, token.Email); err != nil {
+		log.Error("failed to mark email verified after reset", "error", err)
+	}
+	if err := db.RevokeUserRefreshTokens(ctx, token.UserID); err != nil {
+		log.Error("failed to revoke sessions after password reset", "error", err)
+	}
+	// API tokens could have been minted by whoever knew the old password.
+	if err := db.DeleteUserAPITokens(ctx, token.UserID); err != nil {
+		log.Error("failed to revoke API tokens after password reset", "error", err)
+	}
+
+	log.Info("password reset")
+	return c.JSON(fiber.Map{"message": "Password has been reset, please log in"})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestResetPasswordKeepsLinkForRejectedPassword checks that a password
+// rejected for containing the email leaves the reset link usable, and that
+// the link works once.
+func TestResetPasswordKeepsLinkForRejectedPassword(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+	user, _ := createTestUser(t)
+	t.Cleanup(func() {
+		_, _ = db.GetCollection("email_tokens").DeleteMany(context.Background(), bson.M{"user_id": user.ID})
+	})
+
+	token, err := issueEmailToken(ctx, user, models.EmailTokenPasswordReset, passwordResetExpiration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reset := func(password string) (int, string) {
+		return postJSON(t, app, "/auth/reset-password", `{"token":"`+token+`","password":"`+password+`"}`)
+	}
+
+	local, _, _ := strings.Cut(user.Email, "@")
+	if status, body := reset(local + "-9!"); status != fiber.StatusBadRequest || !strings.Contains(body, "email") {
+		t.Fatalf("reset with a password containing the email = %d %s, want 400 about the email", status, body)
+	}
+	if status, body := reset("a-fresh-password-7"); status != fiber.StatusOK {
+		t.Fatalf("reset after a rejected password = %d %s, want 200", status, body)
+	}
+	if status, body := reset("another-password-8"); status != fiber.StatusBadRequest {
+		t.Errorf("second reset with the same link = %d %s, want 400", status, body)
+	}
+}
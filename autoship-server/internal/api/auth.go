@@ -2,64 +2,95 @@
 package api
 
 import (
-	"context"
 	// "fmt"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/gitprovider"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Signup handler
+// dummyPasswordHash is compared against when a login names an unknown email
+// or an account without a password, so that it costs as much as a wrong
+// password.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("autoship-dummy-password"), bcrypt.DefaultCost)
+
+// normalizeEmail lowercases a bare address ("user@example.com") and returns
+// "" if it is not one.
+func normalizeEmail(raw string) string {
+	raw = strings.TrimSpace(raw)
+	addr, err := mail.ParseAddress(raw)
+	if err != nil || addr.Address != raw || addr.Name != "" {
+		return ""
+	}
+	return strings.ToLower(addr.Address)
+}
+
+// Signup creates a password account and mails a verification link. The
+// response is the same whether or not the email already has an account, so
+// it cannot be used to probe for accounts: the owner of an existing one is
+// mailed a notice instead, and the new user signs in with POST /login.
 func Signup(c *fiber.Ctx) error {
-	var user models.User
-	if err := c.BodyParser(&user); err != nil {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
-
-	// Use GetCollection from internal/db/mongo.go
-	collection := db.GetCollection("users")
-
-	// Check if user already exists
-	var existingUser models.User
-	err := collection.FindOne(context.TODO(), bson.M{"email": user.Email}).Decode(&existingUser)
-	if err == nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "User already exists"})
+	email := normalizeEmail(req.Email)
+	if email == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "A valid email is required"})
+	}
+	if err := utils.ValidatePassword(req.Password, email); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	accepted := func() error {
+		return c.Status(http.StatusAccepted).JSON(fiber.Map{"message": "Check your email to finish signing up"})
 	}
 
-	// Hash password before saving
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	// Hashed before the lookup so both outcomes take as long.
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
 	}
-	user.Password = string(hashedPassword)
 
-	// Set timestamps
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	if existing, err := db.GetUserByEmail(c.UserContext(), email); err == nil {
+		sendSignupNotice(c, existing)
+		return accepted()
+	}
 
-	// Insert new user into MongoDB
-	insertResult, err := collection.InsertOne(context.TODO(), user)
-	if err != nil {
+	now := time.Now()
+	user := &models.User{
+		Email:     email,
+		Password:  string(hashedPassword),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := db.CreateUser(c.UserContext(), user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// Lost a race with another signup for the same address.
+			return accepted()
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating user"})
 	}
 
-	// Set ID from InsertedID
-	user.ID = insertResult.InsertedID.(primitive.ObjectID)
-
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID.Hex(), user.Email)
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	// The account is usable right away; verification is needed before
+	// accepting organization invites.
+	if err := sendVerificationEmail(c, user); err != nil {
+		logger.FromCtx(c).Error("failed to issue verification token", "user_id", user.ID.Hex(), "error", err)
 	}
-
-	return c.JSON(fiber.Map{"message": "User registered successfully", "token": token})
+	logger.FromCtx(c).Info("user signed up", "user_id", user.ID.Hex())
+	return accepted()
 }
 
 // Login handler
@@ -73,95 +104,205 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	var user models.User
-	collection := db.GetCollection("users")
-	// Look up user by email
-	err := collection.FindOne(context.TODO(), bson.M{"email": loginDetails.Email}).Decode(&user)
+	email := strings.TrimSpace(loginDetails.Email)
+	lockKey := loginLockKey(strings.ToLower(email))
+	if wait := lockedFor(c, lockKey); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	// Unknown emails and wrong passwords get the same answer, in about the
+	// same time, so logins cannot be used to find registered accounts.
+	invalid := func() error {
+		recordAuthFailure(c, lockKey)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+	}
+
+	// Look up user by email. Accounts from before signups were lowercased
+	// are still found by the address exactly as typed.
+	user, err := db.GetUserByEmail(c.UserContext(), strings.ToLower(email))
+	if err != nil && email != strings.ToLower(email) {
+		user, err = db.GetUserByEmail(c.UserContext(), email)
+	}
 	if err != nil {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(loginDetails.Password))
+		return invalid()
+	}
+
+	// Accounts that only sign in with OAuth or SSO have no password hash,
+	// which bcrypt would reject without hashing; take the same time anyway.
+	if user.Password == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(loginDetails.Password))
+		return invalid()
 	}
 
 	// Compare the hashed password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginDetails.Password)); err != nil {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
+		return invalid()
+	}
+	clearAuthFailures(c, lockKey)
+	if user.TOTPEnabled {
+		return mfaChallengeResponse(c, user.ID)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID.Hex(), user.Email)
+	// Generate access + refresh tokens
+	token, refreshToken, err := issueSession(c.UserContext(), user.ID, user.Email, "")
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
 	}
 
-	return c.JSON(fiber.Map{"message": "Login successful", "token": token})
+	return c.JSON(fiber.Map{"message": "Login successful", "token": token, "refreshToken": refreshToken})
+}
+
+// oauthProvider resolves the :provider route parameter ("github" for the
+// legacy /github/callback route) to a Git provider with OAuth configured.
+func oauthProvider(c *fiber.Ctx) (gitprovider.Provider, bool) {
+	p, ok := gitprovider.Get(c.Params("provider", "github"))
+	if !ok || !p.OAuthConfigured() {
+		return nil, false
+	}
+	return p, true
 }
 
-// GitHubLogin redirects the user to GitHub's OAuth consent page
-func GitHubLogin(c *fiber.Ctx) error {
-	url := utils.GetGitHubAuthURL()
-	return c.Redirect(url, fiber.StatusTemporaryRedirect)
+// OAuthLogin redirects the user to the provider's OAuth consent page
+// (GitHub, GitLab or Bitbucket). With ?link=<ticket> (from POST /auth/link)
+// the flow links the account to the ticket's user instead of signing in.
+func OAuthLogin(c *fiber.Ctx) error {
+	provider, ok := oauthProvider(c)
+	if !ok {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Unknown login provider"})
+	}
+	flow, err := startOAuthFlow(c, c.Query("link"))
+	if err != nil {
+		logger.FromCtx(c).Warn("failed to start OAuth flow", "provider", provider.Name(), "error", err)
+		return oauthErrorRedirect(c, "invalid_request")
+	}
+	return c.Redirect(provider.AuthURL(flow.State, flow.PKCEChallenge()), fiber.StatusTemporaryRedirect)
 }
 
-// GitHubCallback handles GitHub's redirect with the auth code
-func GitHubCallback(c *fiber.Ctx) error {
+// OAuthCallback handles the provider's redirect with the auth code.
+//
+// An account already linked to a user signs that user in. Otherwise a new
+// user is created from the account's verified email, unless a user with that
+// email exists: such accounts are never merged implicitly, the owner has to
+// sign in and link the provider explicitly (see completeExternalLogin).
+func OAuthCallback(c *fiber.Ctx) error {
+	provider, ok := oauthProvider(c)
+	if !ok {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Unknown login provider"})
+	}
+	name := provider.Name()
+	log := logger.With(c, "provider", name)
+	ctx := c.UserContext()
+
+	flow, err := finishOAuthFlow(c)
+	if err != nil {
+		log.Warn("OAuth state check failed", "error", err)
+		return oauthErrorRedirect(c, "invalid_state")
+	}
+	if c.Query("error") != "" {
+		return oauthErrorRedirect(c, "access_denied")
+	}
 	code := c.Query("code")
 	if code == "" {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Authorization code not provided"})
+		return oauthErrorRedirect(c, "invalid_request")
 	}
 
 	// Exchange the code for an access token
-	accessToken, err := utils.ExchangeCodeForAccessToken(code)
+	accessToken, err := provider.Exchange(ctx, code, flow.Verifier)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to exchange code for token"})
+		log.Error("OAuth code exchange failed", "error", err)
+		return oauthErrorRedirect(c, "provider_error")
 	}
 
-	// Get the GitHub user info
-	userInfo, err := utils.GetGitHubUserInfo(accessToken)
+	// Get the provider account
+	identity, err := provider.Identity(ctx, accessToken)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get user info from GitHub"})
+		log.Error("OAuth identity lookup failed", "error", err)
+		return oauthErrorRedirect(c, "provider_error")
 	}
 
-	// Extract email (or fallback)
-	email, ok := userInfo["email"].(string)
-	if !ok || email == "" {
-		email = userInfo["login"].(string) + "@github.com"
+	return completeExternalLogin(c, name, identity, flow.LinkUserID)
+}
+
+// completeExternalLogin signs in, creates or links the user behind a verified
+// external identity (a Git provider or SSO account).
+func completeExternalLogin(c *fiber.Ctx, name string, identity *gitprovider.Identity, linkUserID string) error {
+	log := logger.FromCtx(c)
+	ctx := c.UserContext()
+
+	if linkUserID != "" {
+		return linkIdentity(c, linkUserID, name, identity)
 	}
 
-	// Check if user exists
-	var existingUser models.User
-	collection := db.GetCollection("users")
-	err = collection.FindOne(context.TODO(), bson.M{"email": email}).Decode(&existingUser)
-	if err != nil {
-		// Create new user
-		newUser := models.User{
-			Email:     email,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Password:  "", // GitHub login only
+	// Returning user
+	if user, err := db.GetUserByIdentity(ctx, name, identity.ID); err == nil {
+		return oauthSignIn(c, user)
+	}
+
+	if identity.Email == "" {
+		log.Warn("OAuth account has no verified email", "account_id", identity.ID)
+		return oauthErrorRedirect(c, "no_verified_email")
+	}
+	if existing, err := db.GetUserByEmail(ctx, identity.Email); err == nil {
+		// Accounts created by GitHub logins before account IDs were stored
+		// have no password and no identities; adopt them instead of locking
+		// them out.
+		if existing.Password != "" || len(existing.Identities) > 0 || name != "github" {
+			log.Info("OAuth login matches an existing account, linking required", "account_id", identity.ID)
+			return oauthErrorRedirect(c, "account_exists")
+		}
+		if err := db.LinkIdentity(ctx, existing.ID, name, identity.ID); err != nil {
+			log.Error("failed to link legacy GitHub account", "error", err)
+			return oauthErrorRedirect(c, "server_error")
 		}
-		insertResult, err := collection.InsertOne(context.TODO(), newUser)
-		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
+		if err := db.MarkEmailVerified(ctx, existing.ID, existing.Email); err != nil {
+			log.Error("failed to mark legacy GitHub account verified", "error", err)
 		}
-		newUser.ID = insertResult.InsertedID.(primitive.ObjectID)
-		existingUser = newUser
+		return oauthSignIn(c, existing)
+	}
+
+	// Create new user
+	now := time.Now()
+	newUser := &models.User{
+		Email:           identity.Email,
+		Identities:      map[string]string{name: identity.ID},
+		EmailVerified:   true, // providers only hand out verified emails
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Password:        "", // OAuth login only
+	}
+	if err := db.CreateUser(ctx, newUser); err != nil {
+		log.Error("failed to create OAuth user", "error", err)
+		return oauthErrorRedirect(c, "server_error")
 	}
 
-	// Generate JWT
-	token, err := utils.GenerateJWT(existingUser.ID.Hex(), existingUser.Email)
+	token, refreshToken, err := issueSession(ctx, newUser.ID, newUser.Email, "")
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate JWT"})
+		return oauthErrorRedirect(c, "server_error")
+	}
+	return oauthLoginRedirect(c, token, refreshToken)
+}
+
+// linkIdentity finishes a linking flow started from POST /auth/link.
+func linkIdentity(c *fiber.Ctx, linkUserID, provider string, identity *gitprovider.Identity) error {
+	ctx := c.UserContext()
+	userID, err := primitive.ObjectIDFromHex(linkUserID)
+	if err != nil {
+		return oauthErrorRedirect(c, "invalid_request")
 	}
 
-	// Optional: set as cookie instead of query param
-	// c.Cookie(&fiber.Cookie{
-	//     Name:     "auth_token",
-	//     Value:    token,
-	//     HTTPOnly: true,
-	//     Secure:   false, // true in production
-	//     Path:     "/",
-	// })
+	if existing, err := db.GetUserByIdentity(ctx, provider, identity.ID); err == nil && existing.ID != userID {
+		return oauthErrorRedirect(c, "account_already_linked")
+	}
+	if err := db.LinkIdentity(ctx, userID, provider, identity.ID); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return oauthErrorRedirect(c, "account_already_linked")
+		}
+		logger.FromCtx(c).Error("failed to link account", "error", err)
+		return oauthErrorRedirect(c, "server_error")
+	}
 
-	// Redirect to frontend dashboard with token
-	redirectURL := "http://localhost:3000/dashboard?token=" + token
-	return c.Redirect(redirectURL, fiber.StatusFound)
+	logger.FromCtx(c).Info("external account linked", "user_id", linkUserID, "provider", provider, "account_id", identity.ID)
+	return c.Redirect(utils.FrontendURL()+"/dashboard/settings?linked="+provider, fiber.StatusFound)
 }
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// postJSON sends body to path without credentials.
+func postJSON(t *testing.T, app *fiber.App, path, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func signup(t *testing.T, app *fiber.App, email, password string) (int, string) {
+	t.Helper()
+	return postJSON(t, app, "/signup", `{"email":"`+email+`","password":"`+password+`"}`)
+}
+
+// TestSignupDoesNotRevealAccounts checks that signing up with a taken email
+// answers exactly like a new signup and leaves the existing account alone.
+func TestSignupDoesNotRevealAccounts(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+	email := "signup-" + primitive.NewObjectID().Hex() + "@example.com"
+	t.Cleanup(func() {
+		_, _ = db.GetCollection("users").DeleteMany(context.Background(), bson.M{"email": email})
+	})
+
+	newStatus, newBody := signup(t, app, email, "first-password-1")
+	if newStatus != fiber.StatusAccepted {
+		t.Fatalf("new signup = %d %s, want 202", newStatus, newBody)
+	}
+	user, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("new signup created no user: %v", err)
+	}
+
+	takenStatus, takenBody := signup(t, app, email, "second-password-2")
+	if takenStatus != newStatus || takenBody != newBody {
+		t.Errorf("signup with a taken email = %d %s, want the same as a new signup: %d %s", takenStatus, takenBody, newStatus, newBody)
+	}
+	if strings.Contains(newBody, "token") {
+		t.Errorf("signup response %s carries a token", newBody)
+	}
+
+	after, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.ID != user.ID || bcrypt.CompareHashAndPassword([]byte(after.Password), []byte("first-password-1")) != nil {
+		t.Error("signup with a taken email changed the existing account")
+	}
+}
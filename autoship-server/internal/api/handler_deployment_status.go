@@ -1,9 +1,9 @@
 package api
 
 import (
-	"log"
 	"net/http"
 
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
 	// "github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
 	"github.com/gofiber/fiber/v2"
 )
@@ -24,7 +24,12 @@ func DeploymentStatusHandler(c *fiber.Ctx) error {
 	}
 
 	// Optional: Update MongoDB record or log it
-	log.Printf("Received deployment status: %+v\n", req)
+	logger.FromCtx(c).Info("received deployment status",
+		"deployment_id", req.ID,
+		"status", req.Status,
+		"message", req.Message,
+		"subdomain", req.Subdomain,
+	)
 
 	// You could also persist this status if needed:
 	// err := models.UpdateDeploymentStatus(req.ID, req.Status, req.Message, req.Subdomain)
@@ -0,0 +1,142 @@
+// internal/api/health.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/services"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// readinessTimeout bounds every individual readiness check.
+const readinessTimeout = 5 * time.Second
+
+// defaultAgentMaxAge is how stale the deploy agent's heartbeat may get before
+// the agent is reported down. Override with AGENT_HEARTBEAT_MAX_AGE.
+const defaultAgentMaxAge = 60 * time.Second
+
+// componentStatus is the per-check entry in the /readyz response.
+type componentStatus struct {
+	Status    string `json:"status"` // "ok" or "error"
+	Error     string `json:"error,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// readinessCheck returns a short detail string on success.
+type readinessCheck func(ctx context.Context) (string, error)
+
+// Liveness reports that the process is up and serving requests. It performs
+// no dependency checks so a slow database never gets the server restarted.
+func Liveness(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readiness runs every dependency check concurrently and returns 200 only
+// when all of them pass, 503 otherwise, with per-component results.
+func Readiness(c *fiber.Ctx) error {
+	checks := map[string]readinessCheck{
+		"mongo":        checkMongo,
+		"docker":       services.CheckContainerRuntime,
+		"cloud":        checkCloud,
+		"clone_dir":    checkWritableDir(services.CloneRoot()),
+		"deploy_dir":   checkWritableDir(utils.DeployDir),
+		"log_dir":      checkWritableDir(services.LogDir()),
+		"deploy_agent": checkDeployAgent,
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]componentStatus, len(checks))
+		ready   = true
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check readinessCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.UserContext(), readinessTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := check(ctx)
+			res := componentStatus{Status: "ok", Detail: detail, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				res.Status = "error"
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = res
+			if err != nil {
+				ready = false
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	status, code := "ok", fiber.StatusOK
+	if !ready {
+		status, code = "unavailable", fiber.StatusServiceUnavailable
+	}
+	return c.Status(code).JSON(fiber.Map{"status": status, "checks": results})
+}
+
+func checkMongo(ctx context.Context) (string, error) {
+	return "", db.Ping(ctx)
+}
+
+func checkCloud(ctx context.Context) (string, error) {
+	p := cloud.Get()
+	return p.Name(), p.CheckCredentials(ctx)
+}
+
+// checkWritableDir creates and removes a temp file in dir.
+func checkWritableDir(dir string) readinessCheck {
+	return func(context.Context) (string, error) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return dir, err
+		}
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return dir, fmt.Errorf("%s is not writable: %w", dir, err)
+		}
+		f.Close()
+		return dir, os.Remove(f.Name())
+	}
+}
+
+// checkDeployAgent reads the heartbeat the deploy agent writes next to the
+// request queue and fails when it is missing or too old.
+func checkDeployAgent(context.Context) (string, error) {
+	maxAge := defaultAgentMaxAge
+	if v := os.Getenv("AGENT_HEARTBEAT_MAX_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			maxAge = d
+		}
+	}
+
+	data, err := os.ReadFile(utils.AgentHeartbeatFile)
+	if err != nil {
+		return "", fmt.Errorf("no heartbeat from deploy agent: %w", err)
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("malformed deploy agent heartbeat: %w", err)
+	}
+	age := time.Since(time.Unix(sec, 0)).Round(time.Second)
+	if age > maxAge {
+		return "", fmt.Errorf("deploy agent heartbeat is %s old", age)
+	}
+	return "last heartbeat " + age.String() + " ago", nil
+}
@@ -0,0 +1,14 @@
+// internal/api/jwks.go
+package api
+
+import (
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKS publishes the public keys access tokens are signed with, so the deploy
+// agent and other services can verify tokens without sharing a secret.
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{"keys": utils.PublicJWKS()})
+}
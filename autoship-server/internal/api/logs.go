@@ -0,0 +1,111 @@
+// internal/api/logs.go
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/middleware"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxLogTail caps ?tail so a single request cannot pull an unbounded history.
+const maxLogTail = 10000
+
+// GetProjectLogs returns the build or runtime logs of a project.
+//
+// Query parameters:
+//   - type:   "runtime" (default, `docker logs`) or "build"
+//   - tail:   only the last N lines
+//   - since:  runtime logs newer than a duration ("10m") or timestamp
+//   - follow: stream new runtime lines as Server-Sent Events until the client
+//     disconnects
+func GetProjectLogs(c *fiber.Ctx) error {
+	project := middleware.ProjectFromCtx(c)
+	if project.ContainerName == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Logs are only available for dynamic projects"})
+	}
+
+	opts := services.LogOptions{
+		Follow: c.QueryBool("follow", false),
+		Tail:   c.QueryInt("tail", 0),
+		Since:  strings.TrimSpace(c.Query("since")),
+	}
+	if opts.Tail < 0 || opts.Tail > maxLogTail {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("tail must be between 0 and %d", maxLogTail)})
+	}
+	if strings.HasPrefix(opts.Since, "-") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid since value"})
+	}
+
+	switch c.Query("type", "runtime") {
+	case "build":
+		logs, err := services.BuildLogs(project.Container(), opts)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Build logs not available"})
+		}
+		defer logs.Close()
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendStream(logs)
+	case "runtime":
+		if opts.Follow {
+			return streamContainerLogs(c, project.Container(), opts)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		logs, err := services.ContainerLogs(ctx, project.Container(), opts)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read container logs"})
+		}
+		defer logs.Close()
+		out, _ := io.ReadAll(logs)
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.Send(out)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be \"build\" or \"runtime\""})
+	}
+}
+
+// streamContainerLogs follows `docker logs -f` and forwards every line as an
+// SSE "data:" event. The docker process is killed as soon as a write to the
+// client fails, i.e. when the browser closes the EventSource.
+func streamContainerLogs(c *fiber.Ctx, containerName string, opts services.LogOptions) error {
+	log := logger.FromCtx(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	logs, err := services.ContainerLogs(ctx, containerName, opts)
+	if err != nil {
+		cancel()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read container logs"})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer logs.Close()
+
+		scanner := bufio.NewScanner(logs)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			fmt.Fprintf(w, "data: %s\n\n", scanner.Text())
+			if err := w.Flush(); err != nil {
+				return // client went away
+			}
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			log.Warn("log stream ended", "container", containerName, "error", err)
+		}
+		fmt.Fprint(w, "event: end\ndata: stream closed\n\n")
+		_ = w.Flush()
+	})
+	return nil
+}
@@ -0,0 +1,120 @@
+// internal/api/oauth_flow.go
+package api
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/gitprovider"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/sso"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// oauthFlowCookie holds the signed utils.OAuthFlow between the redirect to an
+// identity provider and its callback.
+const oauthFlowCookie = "oauth_flow"
+
+// startOAuthFlow creates flow state, stores it in the flow cookie and returns
+// it. linkTicket, when non-empty, must be a valid link ticket and turns the
+// flow into an account-linking flow for its user.
+func startOAuthFlow(c *fiber.Ctx, linkTicket string) (*utils.OAuthFlow, error) {
+	linkUserID := ""
+	if linkTicket != "" {
+		uid, err := utils.VerifyLinkTicket(linkTicket)
+		if err != nil {
+			return nil, err
+		}
+		linkUserID = uid
+	}
+
+	flow, err := utils.NewOAuthFlow(linkUserID)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := flow.Sign()
+	if err != nil {
+		return nil, err
+	}
+
+	// SameSite=Lax still sends the cookie on the provider's top-level
+	// redirect back to the callback.
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthFlowCookie,
+		Value:    signed,
+		Path:     "/",
+		MaxAge:   int(utils.OAuthFlowTTL / time.Second),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return flow, nil
+}
+
+// finishOAuthFlow checks the state returned by the provider against the flow
+// cookie and clears the cookie so it cannot be replayed.
+func finishOAuthFlow(c *fiber.Ctx) (*utils.OAuthFlow, error) {
+	cookie := c.Cookies(oauthFlowCookie)
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthFlowCookie,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return utils.VerifyOAuthFlow(cookie, c.Query("state"))
+}
+
+// oauthErrorRedirect sends the browser back to the frontend login page with a
+// short machine-readable error code.
+func oauthErrorRedirect(c *fiber.Ctx, code string) error {
+	return c.Redirect(utils.FrontendURL()+"/login?error="+url.QueryEscape(code), fiber.StatusFound)
+}
+
+// oauthLoginRedirect finishes a browser login: the refresh token goes into its
+// HttpOnly cookie and the access token to the frontend's dashboard.
+func oauthLoginRedirect(c *fiber.Ctx, token, refreshToken string) error {
+	setRefreshCookie(c, refreshToken)
+	return c.Redirect(utils.FrontendURL()+"/dashboard?token="+url.QueryEscape(token), fiber.StatusFound)
+}
+
+// oauthSignIn signs an existing user in after a browser login. Users with 2FA
+// go to the frontend's second-factor page with a challenge token instead.
+func oauthSignIn(c *fiber.Ctx, user *models.User) error {
+	if user.TOTPEnabled {
+		challenge, err := utils.GenerateMFAChallenge(user.ID.Hex())
+		if err != nil {
+			return oauthErrorRedirect(c, "server_error")
+		}
+		return c.Redirect(utils.FrontendURL()+"/login/2fa?challenge="+url.QueryEscape(challenge), fiber.StatusFound)
+	}
+	token, refreshToken, err := issueSession(c.UserContext(), user.ID, user.Email, "")
+	if err != nil {
+		return oauthErrorRedirect(c, "server_error")
+	}
+	return oauthLoginRedirect(c, token, refreshToken)
+}
+
+// LinkTicket returns a URL that starts an account-linking flow for the
+// caller. The frontend navigates the browser to it; ?provider selects the
+// login provider (default "github", or "sso").
+func LinkTicket(c *fiber.Ctx) error {
+	name := c.Query("provider", "github")
+	if name == sso.ProviderName {
+		if !sso.Enabled() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown login provider"})
+		}
+	} else if p, ok := gitprovider.Get(name); !ok || !p.OAuthConfigured() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown login provider"})
+	}
+
+	claims := c.Locals("user").(*utils.Claims)
+	ticket, err := utils.GenerateLinkTicket(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start linking"})
+	}
+	return c.JSON(fiber.Map{"url": c.BaseURL() + "/auth/" + name + "?link=" + url.QueryEscape(ticket)})
+}
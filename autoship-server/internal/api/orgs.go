@@ -0,0 +1,385 @@
+// internal/api/orgs.go
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/mailer"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/middleware"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// inviteExpiration is how long an organization invite can be accepted.
+const inviteExpiration = 7 * 24 * time.Hour
+
+// callerID returns the authenticated user's ObjectID.
+func callerID(c *fiber.Ctx) (primitive.ObjectID, error) {
+	return primitive.ObjectIDFromHex(c.Locals("user").(*utils.Claims).UserID)
+}
+
+// slugify lowercases name and collapses everything but letters and digits
+// into single hyphens.
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// CreateOrg creates an organization with the caller as its owner.
+func CreateOrg(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	var req struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "name is required (max 100 characters)"})
+	}
+	if req.Slug == "" {
+		req.Slug = req.Name
+	}
+	slug := slugify(req.Slug)
+	if slug == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "slug must contain letters or digits"})
+	}
+
+	now := time.Now()
+	org := &models.Organization{Name: req.Name, Slug: slug, CreatedBy: userID, CreatedAt: now, UpdatedAt: now}
+	if err := db.CreateOrg(c.UserContext(), org, userID); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "An organization with this slug already exists"})
+		}
+		logger.FromCtx(c).Error("failed to create organization", "error", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create organization"})
+	}
+
+	logger.FromCtx(c).Info("organization created", "org_id", org.ID.Hex())
+	return c.Status(http.StatusCreated).JSON(db.OrgWithRole{Organization: *org, Role: models.RoleOwner})
+}
+
+// ListOrgs returns the organizations the caller belongs to and their role in each.
+func ListOrgs(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	orgs, err := db.ListOrgsForUser(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch organizations"})
+	}
+	return c.JSON(orgs)
+}
+
+// GetOrg returns one organization the caller belongs to.
+func GetOrg(c *fiber.Ctx) error {
+	membership := middleware.MembershipFromCtx(c)
+	org, err := db.GetOrg(c.UserContext(), membership.OrgID)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+	}
+	return c.JSON(db.OrgWithRole{Organization: *org, Role: membership.Role})
+}
+
+// UpdateOrgSettings changes organization settings; currently only whether
+// members need two-factor authentication. Whoever turns the requirement on
+// must have 2FA themselves so they do not lock themselves out.
+func UpdateOrgSettings(c *fiber.Ctx) error {
+	membership := middleware.MembershipFromCtx(c)
+	ctx := c.UserContext()
+
+	var req struct {
+		Require2FA *bool `json:"require2fa"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Require2FA == nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "require2fa is required"})
+	}
+	if *req.Require2FA {
+		user, err := db.GetUserByID(ctx, membership.UserID)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+		}
+		if !user.TOTPEnabled {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Enable two-factor authentication on your own account first"})
+		}
+	}
+	if err := db.SetOrgRequire2FA(ctx, membership.OrgID, *req.Require2FA); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update organization"})
+	}
+
+	org, err := db.GetOrg(ctx, membership.OrgID)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+	}
+	logger.FromCtx(c).Info("organization 2FA requirement changed", "require_2fa", org.Require2FA)
+	return c.JSON(db.OrgWithRole{Organization: *org, Role: membership.Role})
+}
+
+// GetOrgProjects lists the projects owned by an organization.
+func GetOrgProjects(c *fiber.Ctx) error {
+	projects, err := db.ListProjectsByOrg(c.UserContext(), middleware.MembershipFromCtx(c).OrgID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch projects"})
+	}
+	return c.JSON(projects)
+}
+
+// ListOrgMembers lists an organization's members and their roles.
+func ListOrgMembers(c *fiber.Ctx) error {
+	members, err := db.ListMembers(c.UserContext(), middleware.MembershipFromCtx(c).OrgID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch members"})
+	}
+	return c.JSON(members)
+}
+
+// UpdateOrgMember changes a member's role. Only owners can grant or take away
+// the owner role, and the last owner cannot be demoted.
+func UpdateOrgMember(c *fiber.Ctx) error {
+	caller := middleware.MembershipFromCtx(c)
+	ctx := c.UserContext()
+
+	userID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+	}
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil || !models.ValidRole(req.Role) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "role must be one of owner, admin, developer, viewer"})
+	}
+
+	target, err := db.GetMembership(ctx, caller.OrgID, userID)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+	}
+	if (req.Role == models.RoleOwner || target.Role == models.RoleOwner) && caller.Role != models.RoleOwner {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Only owners can change the owner role"})
+	}
+	if target.Role == models.RoleOwner && req.Role != models.RoleOwner {
+		if sole, err := soleOwner(c, caller.OrgID); err != nil || sole {
+			return lastOwnerResponse(c, err)
+		}
+	}
+
+	if _, err := db.UpdateMemberRole(ctx, caller.OrgID, userID, req.Role); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update member"})
+	}
+	logger.FromCtx(c).Info("organization member role changed", "member_id", userID.Hex(), "role", req.Role)
+	return c.JSON(fiber.Map{"message": "Member updated", "role": req.Role})
+}
+
+// RemoveOrgMember removes a member. Members may always remove themselves;
+// removing someone else needs members:manage, and only owners can remove
+// owners. The last owner cannot leave.
+func RemoveOrgMember(c *fiber.Ctx) error {
+	caller := middleware.MembershipFromCtx(c)
+	ctx := c.UserContext()
+
+	userID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+	}
+	self := userID == caller.UserID
+	if !self && !models.RoleHasPermission(caller.Role, models.PermMembersManage) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Your role does not allow " + models.PermMembersManage})
+	}
+
+	target, err := db.GetMembership(ctx, caller.OrgID, userID)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+	}
+	if target.Role == models.RoleOwner {
+		if !self && caller.Role != models.RoleOwner {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Only owners can remove owners"})
+		}
+		if sole, err := soleOwner(c, caller.OrgID); err != nil || sole {
+			return lastOwnerResponse(c, err)
+		}
+	}
+
+	if _, err := db.RemoveMember(ctx, caller.OrgID, userID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to remove member"})
+	}
+	logger.FromCtx(c).Info("organization member removed", "member_id", userID.Hex())
+	return c.JSON(fiber.Map{"message": "Member removed"})
+}
+
+// soleOwner reports whether orgID has at most one owner.
+func soleOwner(c *fiber.Ctx, orgID primitive.ObjectID) (bool, error) {
+	owners, err := db.CountOwners(c.UserContext(), orgID)
+	return owners <= 1, err
+}
+
+func lastOwnerResponse(c *fiber.Ctx, err error) error {
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check owners"})
+	}
+	return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "An organization must keep at least one owner"})
+}
+
+// CreateInvite invites an email address to the organization. The invite token
+// is returned once; the invitee accepts it with POST /invites/accept.
+func CreateInvite(c *fiber.Ctx) error {
+	caller := middleware.MembershipFromCtx(c)
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if !strings.Contains(req.Email, "@") {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "A valid email is required"})
+	}
+	if req.Role == "" {
+		req.Role = models.RoleDeveloper
+	}
+	if !models.ValidRole(req.Role) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "role must be one of owner, admin, developer, viewer"})
+	}
+	if req.Role == models.RoleOwner && caller.Role != models.RoleOwner {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Only owners can invite owners"})
+	}
+
+	raw, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating invite"})
+	}
+	now := time.Now()
+	invite := &models.Invite{
+		OrgID:     caller.OrgID,
+		Email:     req.Email,
+		Role:      req.Role,
+		TokenHash: utils.HashToken(raw),
+		InvitedBy: caller.UserID,
+		ExpiresAt: now.Add(inviteExpiration),
+		CreatedAt: now,
+	}
+	if err := db.SaveInvite(c.UserContext(), invite); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create invite"})
+	}
+
+	orgName := caller.OrgID.Hex()
+	if org, err := db.GetOrg(c.UserContext(), caller.OrgID); err == nil {
+		orgName = org.Name
+	}
+	link := utils.FrontendURL() + "/invites/accept?token=" + url.QueryEscape(raw)
+	sendMail(c, mailer.Message{
+		To:      invite.Email,
+		Subject: fmt.Sprintf("You have been invited to %s on Auto-Ship", orgName),
+		Body: fmt.Sprintf("You have been invited to join the organization %q on Auto-Ship as %s.\n\n"+
+			"Sign in with this email address and accept the invite here:\n\n%s\n\nThe invite expires in 7 days.\n",
+			orgName, invite.Role, link),
+	})
+
+	logger.FromCtx(c).Info("organization invite created", "invite_id", invite.ID.Hex(), "role", invite.Role)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"invite": invite, "token": raw})
+}
+
+// ListInvites lists an organization's pending invites.
+func ListInvites(c *fiber.Ctx) error {
+	invites, err := db.ListPendingInvites(c.UserContext(), middleware.MembershipFromCtx(c).OrgID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch invites"})
+	}
+	return c.JSON(invites)
+}
+
+// DeleteInvite withdraws a pending invite.
+func DeleteInvite(c *fiber.Ctx) error {
+	inviteID, err := primitive.ObjectIDFromHex(c.Params("inviteId"))
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Invite not found"})
+	}
+	deleted, err := db.DeleteInvite(c.UserContext(), middleware.MembershipFromCtx(c).OrgID, inviteID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete invite"})
+	}
+	if !deleted {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Invite not found"})
+	}
+	return c.JSON(fiber.Map{"message": "Invite deleted"})
+}
+
+// AcceptInvite adds the caller to the organization of an invite token. The
+// caller must be signed in with the invited email.
+func AcceptInvite(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+	userID, err := callerID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	ctx := c.UserContext()
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "token is required"})
+	}
+
+	invite, err := db.FindInvite(ctx, utils.HashToken(req.Token))
+	if err != nil || invite.AcceptedAt != nil || time.Now().After(invite.ExpiresAt) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Invite not found or expired"})
+	}
+	if !strings.EqualFold(invite.Email, claims.Email) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "This invite was sent to a different email address"})
+	}
+	// Otherwise anyone could sign up with the invited address and take the
+	// seat.
+	if user, err := db.GetUserByID(ctx, userID); err != nil || !user.EmailVerified {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Verify your email address before accepting invites"})
+	}
+	if _, err := db.GetMembership(ctx, invite.OrgID, userID); err == nil {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "You are already a member of this organization"})
+	}
+
+	claimed, err := db.MarkInviteAccepted(ctx, invite.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to accept invite"})
+	}
+	if !claimed {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Invite not found or expired"})
+	}
+
+	membership := &models.Membership{OrgID: invite.OrgID, UserID: userID, Role: invite.Role, CreatedAt: time.Now()}
+	if err := db.AddMember(ctx, membership); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "You are already a member of this organization"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to accept invite"})
+	}
+
+	logger.FromCtx(c).Info("organization invite accepted", "org_id", invite.OrgID.Hex(), "role", invite.Role)
+	return c.JSON(fiber.Map{"message": "Invite accepted", "membership": membership})
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/mailer"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ratelimit"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var connectOnce sync.Once
+
+// testApp connects to the MongoDB named by AUTOSHIP_TEST_MONGO_URI and
+// returns the server's routes with ephemeral signing keys. Tests using it are
+// skipped without that variable. They write to its "autoship" database, so
+// point it at a throwaway instance.
+func testApp(t *testing.T) *fiber.App {
+	t.Helper()
+	uri := os.Getenv("AUTOSHIP_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("AUTOSHIP_TEST_MONGO_URI not set")
+	}
+
+	var err error
+	connectOnce.Do(func() {
+		db.SetMongoURI(uri)
+		if err = db.Connect(); err != nil {
+			return
+		}
+		os.Setenv("JWT_KEYS_DIR", "")
+		os.Setenv("JWT_EXPIRATION", "15m")
+		if err = utils.LoadEnv(); err != nil {
+			return
+		}
+		if err = mailer.Init("log"); err != nil {
+			return
+		}
+		err = ratelimit.Init()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	RegisterRoutes(app)
+	return app
+}
+
+// createTestUser saves a user and returns it with an access token.
+func createTestUser(t *testing.T) (*models.User, string) {
+	t.Helper()
+	user := &models.User{
+		Email:     "access-" + primitive.NewObjectID().Hex() + "@example.com",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.GetCollection("users").DeleteOne(context.Background(), bson.M{"_id": user.ID})
+	})
+	token, err := utils.GenerateJWT(user.ID.Hex(), user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+// createTestProject saves p and removes it when the test ends.
+func createTestProject(t *testing.T, p *models.Project) *models.Project {
+	t.Helper()
+	p.ID = primitive.NewObjectID()
+	p.Username = "someone"
+	p.RepoName = "app"
+	p.RepoURL = "https://github.com/someone/app"
+	p.ProjectType = "dynamic"
+	p.ContainerName = "autoship-someone-app-" + p.ID.Hex()
+	p.HostPort = 2000
+	p.Status = models.ProjectStatusRunning
+	p.CreatedAt = time.Now()
+	if err := db.SaveProject(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.GetCollection("projects").DeleteOne(context.Background(), bson.M{"_id": p.ID})
+	})
+	return p
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path, token string) (int, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+// TestProjectRoutesHideOtherUsersProjects checks that every project route
+// answers 404 to a user who neither owns the project nor belongs to its
+// organization, and that nothing was changed.
+func TestProjectRoutesHideOtherUsersProjects(t *testing.T) {
+	app := testApp(t)
+	ctx := context.Background()
+
+	owner, ownerToken := createTestUser(t)
+	_, otherToken := createTestUser(t)
+
+	org := &models.Organization{Name: "Access", Slug: "access-" + primitive.NewObjectID().Hex(), CreatedBy: owner.ID, CreatedAt: time.Now()}
+	if err := db.CreateOrg(ctx, org, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.GetCollection("organizations").DeleteOne(ctx, bson.M{"_id": org.ID})
+		_, _ = db.GetCollection("memberships").DeleteMany(ctx, bson.M{"org_id": org.ID})
+	})
+
+	personal := createTestProject(t, &models.Project{OwnerID: owner.ID})
+	orgProject := createTestProject(t, &models.Project{OwnerID: owner.ID, OrgID: org.ID})
+
+	// The fixtures are reachable by their owner.
+	for _, p := range []*models.Project{personal, orgProject} {
+		if status, body := doRequest(t, app, "GET", "/projects/"+p.ID.Hex(), ownerToken); status != fiber.StatusOK {
+			t.Fatalf("owner GET /projects/%s = %d %s, want 200", p.ID.Hex(), status, body)
+		}
+	}
+
+	for _, p := range []*models.Project{personal, orgProject} {
+		id := p.ID.Hex()
+		for _, r := range []struct{ method, path string }{
+			{"GET", "/projects/" + id},
+			{"PATCH", "/projects/" + id},
+			{"POST", "/projects/" + id + "/redeploy"},
+			{"POST", "/projects/" + id + "/stop"},
+			{"POST", "/projects/" + id + "/start"},
+			{"GET", "/projects/" + id + "/logs"},
+			{"DELETE", "/projects/" + id},
+			{"DELETE", "/projects/" + p.ContainerName},
+		} {
+			status, body := doRequest(t, app, r.method, r.path, otherToken)
+			if status != fiber.StatusNotFound {
+				t.Errorf("%s %s by another user = %d %s, want 404", r.method, r.path, status, body)
+			}
+		}
+
+		after, err := db.GetProjectByID(ctx, id)
+		if err != nil {
+			t.Fatalf("project %s is gone after requests by another user: %v", id, err)
+		}
+		if after.Status != models.ProjectStatusRunning {
+			t.Errorf("project %s status = %q after requests by another user, want %q", id, after.Status, models.ProjectStatusRunning)
+		}
+	}
+
+	status, body := doRequest(t, app, "GET", "/projects", otherToken)
+	if status != fiber.StatusOK {
+		t.Fatalf("GET /projects = %d %s, want 200", status, body)
+	}
+	var listed []models.Project
+	if err := json.Unmarshal(body, &listed); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range listed {
+		if p.ID == personal.ID || p.ID == orgProject.ID {
+			t.Errorf("GET /projects by another user lists project %s", p.ID.Hex())
+		}
+	}
+
+	if status, body := doRequest(t, app, "GET", "/orgs/"+org.ID.Hex()+"/projects", otherToken); status != fiber.StatusNotFound {
+		t.Errorf("GET /orgs/:orgId/projects by a non-member = %d %s, want 404", status, body)
+	}
+}
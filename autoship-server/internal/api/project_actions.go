@@ -0,0 +1,384 @@
+// internal/api/project_actions.go
+package api
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/gitprovider"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/middleware"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ports"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/quota"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/services"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// containerOpTimeout bounds docker stop/start/update calls.
+const containerOpTimeout = 30 * time.Second
+
+// projectAccount returns the quota account a project counts against.
+func projectAccount(p *models.Project) quota.Account {
+	return quota.Account{UserID: p.OwnerID, OrgID: p.OrgID}
+}
+
+// projectMemoryMB is the memory limit the project's container runs with.
+func projectMemoryMB(p *models.Project) int {
+	if p.MemoryMB > 0 {
+		return p.MemoryMB
+	}
+	return quota.DefaultMemoryMB()
+}
+
+// projectDeleting answers requests that would change a project whose deletion
+// has started.
+func projectDeleting(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Project is being deleted"})
+}
+
+// discardContainer removes a container whose deployment failed after it
+// started, and closes and releases its host port.
+func discardContainer(ctx context.Context, containerName string, hostPort int) {
+	log := logger.FromContext(ctx)
+	if err := services.DeleteProject(containerName); err != nil {
+		log.Warn("failed to remove container of failed deployment", "container", containerName, "error", err)
+		return
+	}
+	if err := revokeHostPort(ctx, hostPort); err != nil {
+		log.Warn("failed to close port of failed deployment", "host_port", hostPort, "error", err)
+		return
+	}
+	if err := ports.Release(ctx, hostPort, containerName); err != nil {
+		log.Warn("failed to release port of failed deployment", "host_port", hostPort, "error", err)
+	}
+}
+
+// retireContainer removes the container a redeploy replaced along with its
+// image and build logs, then closes and releases its host port. The port is
+// kept unless the container was found and removed: it may still be bound.
+func retireContainer(ctx context.Context, containerName string, hostPort int) {
+	log := logger.FromContext(ctx)
+	exists, err := services.ContainerExists(ctx, containerName)
+	if err != nil || !exists {
+		log.Warn("previous container not found, keeping its port", "container", containerName, "host_port", hostPort, "error", err)
+		return
+	}
+	if err := services.DeleteProject(containerName); err != nil {
+		log.Warn("failed to remove previous container", "container", containerName, "error", err)
+		return
+	}
+	if err := services.RemoveImage(ctx, containerName); err != nil {
+		log.Warn("failed to remove previous image", "container", containerName, "error", err)
+	}
+	if err := services.RemoveBuildLogs(containerName); err != nil {
+		log.Warn("failed to remove previous build logs", "container", containerName, "error", err)
+	}
+	if hostPort == 0 {
+		return
+	}
+	if err := revokeHostPort(ctx, hostPort); err != nil {
+		log.Warn("failed to close previous port", "host_port", hostPort, "error", err)
+	} else if err := ports.Release(ctx, hostPort, containerName); err != nil {
+		log.Warn("failed to release previous port", "host_port", hostPort, "error", err)
+	}
+}
+
+// GetProject returns a single project. Access has already been checked by
+// middleware.RequireProjectPermission.
+func GetProject(c *fiber.Ctx) error {
+	return c.JSON(middleware.ProjectFromCtx(c))
+}
+
+// updateProjectRequest is the body of PATCH /projects/:id. Omitted fields are
+// left unchanged.
+type updateProjectRequest struct {
+	StartCommand *string `json:"startCommand"`
+	EnvContent   *string `json:"envContent"`
+	Branch       *string `json:"branch"` // "" switches back to the default branch
+	MemoryMB     *int    `json:"memoryMb"`
+}
+
+// UpdateProject changes a project's settings. A new memory limit is applied
+// to the running container right away; the start command, .env and branch
+// take effect on the next redeploy.
+func UpdateProject(c *fiber.Ctx) error {
+	project := middleware.ProjectFromCtx(c)
+	if project.Deleting() {
+		return projectDeleting(c)
+	}
+	log := logger.FromCtx(c)
+
+	var req updateProjectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	dynamic := project.ProjectType == "dynamic"
+	if !dynamic && (req.StartCommand != nil || req.EnvContent != nil || req.MemoryMB != nil) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Static projects only support changing the branch"})
+	}
+	if req.StartCommand != nil && *req.StartCommand == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "startCommand cannot be empty"})
+	}
+	if req.Branch != nil && *req.Branch != "" && !services.ValidBranchName(*req.Branch) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid branch name"})
+	}
+	if req.MemoryMB != nil && *req.MemoryMB < 64 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "memoryMb must be at least 64"})
+	}
+
+	if req.MemoryMB != nil && *req.MemoryMB != projectMemoryMB(project) {
+		// A stopped container holds no memory; its new limit is checked
+		// when it is started again.
+		if !project.Stopped() {
+			releaseMemory, err := quota.ReserveMemoryChange(c.UserContext(), projectAccount(project), projectMemoryMB(project), *req.MemoryMB)
+			if err != nil {
+				return quotaError(c, err)
+			}
+			defer releaseMemory()
+		}
+		ctx, cancel := context.WithTimeout(c.UserContext(), containerOpTimeout)
+		defer cancel()
+		if err := services.UpdateContainerMemory(ctx, project.Container(), *req.MemoryMB); err != nil {
+			log.Error("failed to update container memory", "container", project.Container(), "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update container memory"})
+		}
+	}
+
+	if err := db.UpdateProjectSettings(c.UserContext(), project.ID, db.ProjectSettings{
+		StartCommand: req.StartCommand,
+		EnvContent:   req.EnvContent,
+		Branch:       req.Branch,
+		MemoryMB:     req.MemoryMB,
+	}); err != nil {
+		log.Error("failed to update project", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update project"})
+	}
+
+	updated, err := db.GetProjectByID(c.UserContext(), project.ID.Hex())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load project"})
+	}
+	return c.JSON(fiber.Map{
+		"project":          updated,
+		"redeployRequired": req.StartCommand != nil || req.EnvContent != nil || req.Branch != nil,
+	})
+}
+
+// redeployRequest is the optional body of POST /projects/:id/redeploy.
+type redeployRequest struct {
+	// AccessToken clones a private repository, as in RepoRequest. It is
+	// never stored, so redeploys of private repositories must resend it.
+	AccessToken string `json:"accessToken,omitempty"`
+}
+
+// RedeployProject pulls the latest commit of the project's branch and
+// deploys it again with the project's current settings. Dynamic projects get
+// a new container that replaces the old one once the deploy agent points
+// the subdomain at it; a failed redeploy leaves the old container running.
+func RedeployProject(c *fiber.Ctx) error {
+	project := middleware.ProjectFromCtx(c)
+	if project.Deleting() {
+		return projectDeleting(c)
+	}
+
+	var req redeployRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+	}
+
+	account := projectAccount(project)
+	memoryMB := projectMemoryMB(project)
+	if project.ProjectType == "dynamic" && project.Stopped() {
+		// Redeploying starts the project again.
+		releaseContainer, err := quota.ReserveContainer(c.UserContext(), account, memoryMB)
+		if err != nil {
+			return quotaError(c, err)
+		}
+		defer releaseContainer()
+	}
+	releaseBuild, err := quota.StartBuild(c.UserContext(), account)
+	if err != nil {
+		return quotaError(c, err)
+	}
+	defer releaseBuild()
+
+	deploymentID := utils.GenerateRandomID()
+	log := logger.With(c, "deployment_id", deploymentID, "repo_url", project.RepoURL)
+	ctx := logger.NewContext(c.UserContext(), log)
+
+	repo, provider, err := gitprovider.ParseRepoURL(project.RepoURL)
+	if err != nil {
+		log.Error("stored repository URL no longer parses", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Invalid repository URL"})
+	}
+	var creds *services.GitCredentials
+	if req.AccessToken != "" {
+		user, pass := provider.CloneCredentials(req.AccessToken)
+		creds = &services.GitCredentials{Username: user, Password: pass}
+	}
+
+	path, err := services.CloneRepository(ctx, repo.CloneURL, repo.Name, project.Branch, creds)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	defer services.RemoveCloneDir(path)
+
+	environment := "static"
+	if project.ProjectType == "dynamic" {
+		environment = string(services.DetectEnvironment(path))
+	}
+	metrics.DeploymentsTotal.WithLabelValues(project.ProjectType, environment, metrics.DeploymentStarted).Inc()
+	succeeded := false
+	defer func() {
+		status := metrics.DeploymentFailed
+		if succeeded {
+			status = metrics.DeploymentSucceeded
+		}
+		metrics.DeploymentsTotal.WithLabelValues(project.ProjectType, environment, status).Inc()
+	}()
+
+	updated := *project
+	if project.ProjectType == "static" {
+		size, err := services.StaticSiteSize(path)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to measure site size: "+err.Error())
+		}
+		releaseStorage, err := quota.ReserveStaticStorage(ctx, account, size-project.StorageBytes)
+		if err != nil {
+			return quotaError(c, err)
+		}
+		defer releaseStorage()
+		// Sites still under the shared <owner>/<repo> prefix move to their own.
+		if updated.StoragePrefix == "" {
+			updated.StoragePrefix = siteKeyPrefix(project.ID)
+		}
+		url, err := cloud.Get().UploadStaticSite(ctx, path, updated.StoragePrefix)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to upload to S3: "+err.Error())
+		}
+		updated.HostedURL = url
+		updated.StorageBytes = size
+	} else {
+		containerPort, hostPort, containerName, err := services.FullPipeline(ctx, repo.Owner, path, project.EnvContent, project.StartCommand, memoryMB)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to deploy dynamic project: "+err.Error())
+		}
+
+		// Keep the project's address; the agent replaces its configuration.
+		subdomain := projectSubdomain(project)
+		if subdomain == "" {
+			subdomain = utils.GenerateSubdomain(repo.Name, os.Getenv("DOMAIN"))
+		}
+		hostedURL, err := registerWithAgent(ctx, deploymentID, subdomain, project.ProjectType, hostPort)
+		if err != nil {
+			discardContainer(ctx, containerName, hostPort)
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+		updated.HostedURL = hostedURL
+		updated.Subdomain = subdomain
+		updated.ContainerName = containerName
+		updated.ContainerPort = containerPort
+		updated.HostPort = hostPort
+		updated.BuildLogPath = services.BuildLogPath(containerName)
+		updated.Status = models.ProjectStatusRunning
+	}
+
+	if err := db.UpdateProjectDeployment(ctx, &updated); err != nil {
+		log.Error("failed to save redeployed project", "error", err)
+		if project.ProjectType == "dynamic" {
+			// The record still names the old container; send traffic back
+			// to it before dropping the new one.
+			if project.HostPort != 0 {
+				if _, err := registerWithAgent(ctx, utils.GenerateRandomID(), updated.Subdomain, project.ProjectType, project.HostPort); err != nil {
+					log.Error("failed to point proxy back at previous container", "error", err)
+				}
+			}
+			discardContainer(ctx, updated.ContainerName, updated.HostPort)
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save project")
+	}
+	succeeded = true
+
+	if project.ProjectType == "dynamic" && project.ContainerName != "" {
+		// Traffic now goes to the new container; free what the old one held.
+		retireContainer(ctx, project.Container(), project.HostPort)
+	}
+	if project.ProjectType == "static" && project.StoragePrefix == "" {
+		if err := deleteStaticSite(ctx, project); err != nil {
+			log.Warn("failed to delete previous site files", "error", err)
+		}
+	}
+
+	log.Info("project redeployed", "project_id", project.ID.Hex())
+	return c.JSON(fiber.Map{
+		"message": "Project redeployed successfully",
+		"url":     updated.HostedURL,
+	})
+}
+
+// StopProject stops a dynamic project's container without removing it. A
+// stopped project no longer counts as a running container.
+func StopProject(c *fiber.Ctx) error {
+	project := middleware.ProjectFromCtx(c)
+	if project.Deleting() {
+		return projectDeleting(c)
+	}
+	if project.ProjectType != "dynamic" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only dynamic projects can be stopped"})
+	}
+	if project.Stopped() {
+		return c.JSON(fiber.Map{"message": "Project is already stopped", "status": project.Status})
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), containerOpTimeout)
+	defer cancel()
+	if err := services.StopContainer(ctx, project.Container()); err != nil {
+		logger.FromCtx(c).Error("failed to stop container", "container", project.Container(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to stop project"})
+	}
+	if err := db.SetProjectStatus(c.UserContext(), project.ID, models.ProjectStatusStopped); err != nil {
+		logger.FromCtx(c).Error("failed to record project status", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update project"})
+	}
+	return c.JSON(fiber.Map{"message": "Project stopped", "status": models.ProjectStatusStopped})
+}
+
+// StartProject starts a container stopped by StopProject, subject to the
+// account's running container and memory quotas.
+func StartProject(c *fiber.Ctx) error {
+	project := middleware.ProjectFromCtx(c)
+	if project.Deleting() {
+		return projectDeleting(c)
+	}
+	if project.ProjectType != "dynamic" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only dynamic projects can be started"})
+	}
+	if !project.Stopped() {
+		return c.JSON(fiber.Map{"message": "Project is already running", "status": models.ProjectStatusRunning})
+	}
+	releaseContainer, err := quota.ReserveContainer(c.UserContext(), projectAccount(project), projectMemoryMB(project))
+	if err != nil {
+		return quotaError(c, err)
+	}
+	defer releaseContainer()
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), containerOpTimeout)
+	defer cancel()
+	if err := services.StartContainer(ctx, project.Container()); err != nil {
+		logger.FromCtx(c).Error("failed to start container", "container", project.Container(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start project"})
+	}
+	if err := db.SetProjectStatus(c.UserContext(), project.ID, models.ProjectStatusRunning); err != nil {
+		logger.FromCtx(c).Error("failed to record project status", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update project"})
+	}
+	return c.JSON(fiber.Map{"message": "Project started", "status": models.ProjectStatusRunning})
+}
@@ -2,18 +2,24 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/gitprovider"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/middleware"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/quota"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/services"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/tracing"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
 	"os"
 	// "os/exec"
-	"strings"
 	"time"
 )
 
@@ -22,7 +28,18 @@ type RepoRequest struct {
 	RepoURL      string `json:"repoURL"`
 	EnvContent   string `json:"envContent,omitempty"` // Optional field for .env content
 	StartCommand string `json:"startCommand"`
-	// IN future, add fields for branch, commit
+	OrgID        string `json:"orgId,omitempty"` // deploy into this organization instead of the user's account
+	// AccessToken clones a private repository: a provider OAuth token or
+	// access token (Bitbucket app passwords as "username:password"). It is
+	// used for this clone only and never stored.
+	AccessToken string `json:"accessToken,omitempty"`
+	// MemoryMB caps the container's memory for dynamic projects; it counts
+	// against the account's memory quota. Defaults to
+	// DEFAULT_CONTAINER_MEMORY_MB.
+	MemoryMB int `json:"memoryMb,omitempty"`
+	// Branch deploys a branch other than the repository's default one.
+	Branch string `json:"branch,omitempty"`
+	// IN future, add a field for commit
 
 }
 
@@ -36,58 +53,142 @@ func HandleRepoSubmit(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request")
 	}
 	if req.RepoURL == "" {
-		log.Println("repoURL not provided in request body")
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "repoURL is required"})
 	}
+	if req.Branch != "" && !services.ValidBranchName(req.Branch) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid branch name"})
+	}
+	claims := c.Locals("user").(*utils.Claims)
+	ownerID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	var orgID primitive.ObjectID
+	if req.OrgID != "" {
+		orgID, err = primitive.ObjectIDFromHex(req.OrgID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		membership, err := db.GetMembership(c.UserContext(), orgID, ownerID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		if !models.RoleHasPermission(membership.Role, models.PermProjectsDeploy) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Your role does not allow " + models.PermProjectsDeploy})
+		}
+		if ok, err := middleware.TwoFactorSatisfied(c.UserContext(), membership); err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Authorization temporarily unavailable"})
+		} else if !ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "This organization requires two-factor authentication; enable it in your account settings"})
+		}
+	}
 
-	// fmt.Println("Received repo URL:", req.RepoURL)
+	if req.MemoryMB == 0 {
+		req.MemoryMB = quota.DefaultMemoryMB()
+	}
+	if req.MemoryMB < 64 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "memoryMb must be at least 64"})
+	}
 
-	// Extract username from the GitHub repo URL
-	username, err := utils.ExtractUsernameFromRepoURL(req.RepoURL)
+	// Quotas: the project and a build slot are reserved up front, the
+	// container or storage once the project type is known. Reservations are
+	// held until the project is saved.
+	account := quota.Account{UserID: ownerID, OrgID: orgID}
+	releaseProject, err := quota.ReserveNewProject(c.UserContext(), account)
 	if err != nil {
-		log.Printf("Error extracting username: %v", err)
+		return quotaError(c, err)
+	}
+	defer releaseProject()
+	releaseBuild, err := quota.StartBuild(c.UserContext(), account)
+	if err != nil {
+		return quotaError(c, err)
+	}
+	defer releaseBuild()
+
+	// Every log line of this deployment carries the same deployment_id, which
+	// is also the request ID handed to the deploy agent.
+	deploymentID := utils.GenerateRandomID()
+	log := logger.With(c, "deployment_id", deploymentID, "repo_url", req.RepoURL)
+	ctx := logger.NewContext(c.UserContext(), log)
+
+	// Resolve the Git provider, owner and repo name from the URL
+	repo, provider, err := gitprovider.ParseRepoURL(req.RepoURL)
+	if err != nil {
+		log.Warn("invalid repository URL", "error", err)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	username, repoName := repo.Owner, repo.Name
 
-	// Extract repo name from the URL
-	parts := strings.Split(strings.TrimSuffix(req.RepoURL, ".git"), "/")
-	repoName := parts[len(parts)-1]
+	var creds *services.GitCredentials
+	if req.AccessToken != "" {
+		user, pass := provider.CloneCredentials(req.AccessToken)
+		creds = &services.GitCredentials{Username: user, Password: pass}
+	}
 
 	// Clone the repository
-	path, err := services.CloneRepository(req.RepoURL, username, repoName)
+	path, err := services.CloneRepository(ctx, repo.CloneURL, repoName, req.Branch, creds)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
+	defer services.RemoveCloneDir(path)
 
 	// Detect the type of the project (static or dynamic)
 	projectType := services.DetectProjectType(path)
 	if projectType == "unknown" {
-		// _ = os.RemoveAll(path)
 		return fiber.NewError(fiber.StatusBadRequest, "Unknown project type. Please ensure the repository contains a valid project structure.")
 	}
-	fmt.Println("Project type detected:", projectType)
-	var hostedURL string
+	environment := "static"
+	if projectType == "dynamic" {
+		environment = string(services.DetectEnvironment(path))
+	}
+	log.Info("project type detected", "project_type", projectType, "environment", environment)
+
+	metrics.DeploymentsTotal.WithLabelValues(projectType, environment, metrics.DeploymentStarted).Inc()
+	succeeded := false
+	defer func() {
+		status := metrics.DeploymentFailed
+		if succeeded {
+			status = metrics.DeploymentSucceeded
+		}
+		metrics.DeploymentsTotal.WithLabelValues(projectType, environment, status).Inc()
+	}()
+
+	projectID := primitive.NewObjectID()
+	var hostedURL, storagePrefix string
 	var containerPort, hostPort int
-	var containerName string
+	var containerName, subdomain string
 	// If the project is static, upload to S3/Blob and generate a hosted URL
+	var storageBytes int64
 	if projectType == "static" {
-		keyPrefix := fmt.Sprintf("%s/%s", username, repoName)
-		url, err := cloud.Get().UploadStaticSite(path, keyPrefix)
+		storageBytes, err = services.StaticSiteSize(path)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to measure site size: "+err.Error())
+		}
+		releaseStorage, err := quota.ReserveStaticStorage(ctx, account, storageBytes)
+		if err != nil {
+			return quotaError(c, err)
+		}
+		defer releaseStorage()
+		storagePrefix = siteKeyPrefix(projectID)
+		url, err := cloud.Get().UploadStaticSite(ctx, path, storagePrefix)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Failed to upload to S3: "+err.Error())
 		}
-		_ = os.RemoveAll(path)
 
 		//when returning the s3 url shorten it and encrypt it and in future all links we be routed through a proxy server
 		hostedURL = url
 	} else {
+		releaseContainer, err := quota.ReserveContainer(ctx, account, req.MemoryMB)
+		if err != nil {
+			return quotaError(c, err)
+		}
+		defer releaseContainer()
 		// Run FullPipeline to detect environment, write Dockerfile, build & run
-		containerPort, hostPort, containerName, err = services.FullPipeline(username, path, req.EnvContent, req.StartCommand)
+		containerPort, hostPort, containerName, err = services.FullPipeline(ctx, username, path, req.EnvContent, req.StartCommand, req.MemoryMB)
 		// returns hostPort
-		subdomain := utils.GenerateSubdomain(repoName, domain)
+		subdomain = utils.GenerateSubdomain(repoName, domain)
 		// subdomain := fmt.Sprintf("%s.%s", repoName, domain)+
 		if err != nil {
-			_ = os.RemoveAll(path)
 			return fiber.NewError(fiber.StatusInternalServerError, "Failed to deploy dynamic project: "+err.Error())
 		}
 
@@ -97,38 +198,26 @@ func HandleRepoSubmit(c *fiber.Ctx) error {
 		}
 
 		// hostedURL = fmt.Sprintf("http://%s:%d", ec2Host, hostPort)
-		hostedURL = fmt.Sprintf("https://%s", subdomain)
-		// Step 1: Create deployment request JSON
-		requestID := utils.GenerateRandomID()
-		deployRequest := map[string]interface{}{
-			"id":          requestID,
-			"subdomain":   subdomain,
-			"projectType": projectType,
-			"port":         hostPort,
-			"status":      "pending",
-		}
-
-		// Step 2: Append to /tmp/deploy-requests.json
-		if err := utils.AppendJSONToFile("/var/lib/autoship/deploy/deploy-requests.json", deployRequest); err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Failed to queue deployment: "+err.Error())
-		}
-
-		// Step 3: Wait for response (polling with timeout)
-		response, err := utils.WaitForResponse("/var/lib/autoship/deploy/deploy-responses.json", requestID, 60*time.Second)
-		if err != nil || response["status"] != "success" {
-			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Deployment failed: %v", response["error"]))
+		hostedURL, err = registerWithAgent(ctx, deploymentID, subdomain, projectType, hostPort)
+		if err != nil {
+			discardContainer(ctx, containerName, hostPort)
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 		}
-		hostedURL = response["url"].(string)
 	}
 
 	// encrypt the hosted URL for security
 	// Create a new project model
 	project := &models.Project{
+		ID:          projectID,
+		OwnerID:     ownerID,
+		OrgID:       orgID,
 		Username:    username,
 		RepoURL:     req.RepoURL,
 		RepoName:    repoName,
+		Branch:      req.Branch,
 		ProjectType: projectType,
 		HostedURL:   hostedURL,
+		Subdomain:   subdomain,
 		// add columns for createdAt, updatedAt, etc.
 		// ports will be added in future
 		// start command: "",
@@ -136,14 +225,34 @@ func HandleRepoSubmit(c *fiber.Ctx) error {
 		ContainerPort: containerPort,
 		HostPort:      hostPort,
 		ContainerName: containerName,
+		StorageBytes:  storageBytes,
+		StoragePrefix: storagePrefix,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
 
+	if containerName != "" {
+		project.BuildLogPath = services.BuildLogPath(containerName)
+		project.MemoryMB = req.MemoryMB
+		project.Status = models.ProjectStatusRunning
+		project.EnvContent = req.EnvContent
+	}
+
 	// Save the project details to the database
-	if err := db.SaveProject(project); err != nil {
+	if err := db.SaveProject(ctx, project); err != nil {
+		log.Error("failed to save project", "error", err)
+		// Without a record nothing could delete the deployment later.
+		if containerName != "" {
+			if err := unregisterWithAgent(ctx, utils.GenerateRandomID(), subdomain); err != nil {
+				log.Warn("failed to remove proxy configuration of unsaved project", "subdomain", subdomain, "error", err)
+			}
+			discardContainer(ctx, containerName, hostPort)
+		} else if err := deleteStaticSite(ctx, project); err != nil {
+			log.Warn("failed to delete site files of unsaved project", "key_prefix", storagePrefix, "error", err)
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save project")
 	}
+	succeeded = true
 
 	// Return a success response with project details
 	return c.JSON(fiber.Map{
@@ -153,46 +262,115 @@ func HandleRepoSubmit(c *fiber.Ctx) error {
 	})
 }
 
-// GetUserProjects fetches all projects belonging to the authenticated user
-func GetUserProjects(c *fiber.Ctx) error {
-	// import your utils package if not already imported
-	claims := c.Locals("user").(*utils.Claims)
-	username := claims.Email // or claims.UserID, depending on what you want
+// registerWithAgent asks the deploy agent to point subdomain at hostPort
+// (nginx, DNS and TLS) and waits for it to answer. It returns the public URL
+// the agent reports, or https://<subdomain>. Registering an existing
+// subdomain again replaces its configuration.
+func registerWithAgent(ctx context.Context, requestID, subdomain, projectType string, hostPort int) (string, error) {
+	// Step 1: Create deployment request JSON
+	deployRequest := map[string]interface{}{
+		"id":          requestID,
+		"subdomain":   subdomain,
+		"projectType": projectType,
+		"port":        hostPort,
+		"host":        services.UpstreamHost(),
+		"status":      "pending",
+	}
 
-	collection := db.GetCollection("projects")
-	cursor, err := collection.Find(c.Context(), bson.M{"username": username})
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch projects"})
+	// Step 2: Append to /tmp/deploy-requests.json
+	if err := utils.AppendJSONToFile(utils.DeployRequestsFile, deployRequest); err != nil {
+		return "", fmt.Errorf("Failed to queue deployment: %v", err)
 	}
-	defer cursor.Close(c.Context())
 
-	var projects []bson.M
-	if err := cursor.All(c.Context(), &projects); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to decode projects"})
+	// Step 3: Wait for response (polling with timeout)
+	_, waitSpan := tracing.Start(ctx, "deploy_agent.wait")
+	response, err := utils.WaitForResponse(utils.DeployResponsesFile, requestID, 60*time.Second)
+	tracing.End(waitSpan, err)
+	if err != nil || response["status"] != "success" {
+		return "", fmt.Errorf("Deployment failed: %v", response["error"])
 	}
+	if url, ok := response["url"].(string); ok && url != "" {
+		return url, nil
+	}
+	return fmt.Sprintf("https://%s", subdomain), nil
+}
 
-	return c.JSON(projects)
+// siteKeyPrefix is the object-key prefix a static project's files are
+// uploaded under. It is unique per project, so deleting one project's site
+// never touches another's.
+func siteKeyPrefix(id primitive.ObjectID) string {
+	return id.Hex()
+}
+
+// deleteStaticSite removes p's uploaded files. Sites uploaded before projects
+// had their own prefix are left alone while another project still uses them.
+func deleteStaticSite(ctx context.Context, p *models.Project) error {
+	if p.StoragePrefix != "" {
+		return cloud.Get().DeleteStaticSite(ctx, p.StoragePrefix)
+	}
+	shared, err := db.LegacySiteShared(ctx, p.Username, p.RepoName, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check other projects' use of the site files: %w", err)
+	}
+	prefix := fmt.Sprintf("%s/%s", p.Username, p.RepoName)
+	if shared {
+		logger.FromContext(ctx).Warn("leaving static site files used by other projects", "key_prefix", prefix)
+		return nil
+	}
+	return cloud.Get().DeleteStaticSite(ctx, prefix)
 }
 
-// DeleteDeployment deletes a project deployment by container name
-func DeleteDeployment(c *fiber.Ctx) error {
-	containerName := c.Params("containerName")
-	if containerName == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "containerName is required")
+// projectSubdomain returns the subdomain the deploy agent serves p on.
+// Projects deployed before it was stored fall back to the host of their URL.
+func projectSubdomain(p *models.Project) string {
+	if p.Subdomain != "" {
+		return p.Subdomain
+	}
+	if u, err := url.Parse(p.HostedURL); err == nil {
+		return u.Hostname()
 	}
+	return ""
+}
 
-	// Use the new service to delete the project
-	if err := services.DeleteProject(containerName); err != nil {
-		log.Printf("Failed to delete project deployment for container %s: %v", containerName, err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete deployment")
+// unregisterWithAgent asks the deploy agent to remove everything it set up
+// for subdomain (nginx site, DNS record and certificate) and waits for it to
+// finish. Removing a subdomain that is not configured succeeds.
+func unregisterWithAgent(ctx context.Context, requestID, subdomain string) error {
+	deleteRequest := map[string]interface{}{
+		"id":        requestID,
+		"action":    "delete",
+		"subdomain": subdomain,
+		"status":    "pending",
+	}
+	if err := utils.AppendJSONToFile(utils.DeployRequestsFile, deleteRequest); err != nil {
+		return fmt.Errorf("failed to queue removal: %v", err)
 	}
 
-	// Remove from DB
-	if err := db.DeleteProjectByContainerName(containerName); err != nil {
-		log.Printf("Failed to delete project from DB for container %s: %v", containerName, err)
-		// Depending on desired behavior, you might want to return an error here
-		// For now, we log it and consider the primary operation (container removal) successful.
+	_, waitSpan := tracing.Start(ctx, "deploy_agent.wait")
+	response, err := utils.WaitForResponse(utils.DeployResponsesFile, requestID, 60*time.Second)
+	tracing.End(waitSpan, err)
+	if err != nil {
+		return fmt.Errorf("deploy agent did not answer: %v", err)
 	}
+	if response["status"] != "success" {
+		return fmt.Errorf("deploy agent failed to remove %s: %v", subdomain, response["error"])
+	}
+	return nil
+}
 
-	return c.JSON(fiber.Map{"message": "Deployment deleted successfully"})
+// GetUserProjects fetches the personal projects of the authenticated user.
+// Organization projects are listed by GET /orgs/:orgId/projects.
+func GetUserProjects(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+	ownerID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	projects, err := db.ListProjectsByOwner(c.UserContext(), ownerID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch projects"})
+	}
+
+	return c.JSON(projects)
 }
@@ -2,7 +2,11 @@ package api
 
 import (
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/middleware"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ratelimit"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // RegisterRoutes wires every HTTP route exposed by the server.
@@ -17,7 +21,14 @@ import (
 // stay shared) is not versioning, it's renaming — don't bother until the
 // handler split is real.
 func RegisterRoutes(app *fiber.App) {
-	app.Get("/health", healthCheck)
+	// /health is kept for existing probes; new deployments should use
+	// /healthz (liveness) and /readyz (readiness).
+	app.Get("/health", Liveness)
+	app.Get("/healthz", Liveness)
+	app.Get("/readyz", Readiness)
+	app.Get("/ping", Ping)
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+	app.Get("/.well-known/jwks.json", JWKS)
 
 	app.Static("/static", "./static", fiber.Static{
 		Browse:   true,
@@ -27,24 +38,92 @@ func RegisterRoutes(app *fiber.App) {
 	app.Get("/autoship-server/static/:username/:repo/*", redirectLegacyStatic)
 
 	registerAuthRoutes(app)
+	registerTokenRoutes(app)
+	registerOrgRoutes(app)
 	registerProjectRoutes(app)
 }
 
 func registerAuthRoutes(app *fiber.App) {
-	app.Post("/signup", Signup)
-	app.Post("/login", Login)
-	app.Get("/auth/github", GitHubLogin)
-	app.Get("/github/callback", GitHubCallback)
+	// Endpoints that check credentials or send email are throttled per IP on
+	// top of the per-account lockout.
+	throttled := middleware.RateLimitByIP(ratelimit.Auth)
+
+	app.Post("/signup", throttled, Signup)
+	app.Post("/login", throttled, Login)
+	app.Post("/auth/login/2fa", throttled, LoginTwoFactor)
+	// Registered before /auth/:provider, which would otherwise take
+	// GET /auth/2fa.
+	twoFactor := app.Group("/auth/2fa", middleware.IsAuthenticated, middleware.RequireSession)
+	twoFactor.Get("/", TwoFactorStatus)
+	twoFactor.Post("/enroll", StartTwoFactorEnrollment)
+	twoFactor.Post("/confirm", ConfirmTwoFactorEnrollment)
+	twoFactor.Post("/disable", DisableTwoFactor)
+	twoFactor.Post("/recovery-codes", RegenerateRecoveryCodes)
+	// /github/callback is the redirect URI existing GitHub OAuth apps are
+	// registered with; other providers use /auth/:provider/callback.
+	app.Get("/github/callback", OAuthCallback)
+	app.Get("/auth/providers", LoginProviders)
+	app.Get("/auth/sso", SSOLogin)
+	app.Get("/auth/sso/callback", SSOCallback)
+	app.Get("/auth/:provider", OAuthLogin)
+	app.Get("/auth/:provider/callback", OAuthCallback)
+	app.Post("/auth/link", middleware.IsAuthenticated, middleware.RequireSession, LinkTicket)
+	app.Post("/auth/refresh", RefreshSession)
+	app.Post("/auth/logout", middleware.IsAuthenticated, middleware.RequireSession, Logout)
+	app.Post("/auth/verify-email", throttled, VerifyEmail)
+	app.Post("/auth/verify-email/resend", throttled, middleware.IsAuthenticated, middleware.RequireSession, ResendVerification)
+	app.Post("/auth/forgot-password", throttled, ForgotPassword)
+	app.Post("/auth/reset-password", throttled, ResetPassword)
 }
 
-func registerProjectRoutes(app *fiber.App) {
-	app.Post("/projects/submit", middleware.IsAuthenticated, HandleRepoSubmit)
-	app.Get("/projects", middleware.IsAuthenticated, GetUserProjects)
-	app.Delete("/projects/:containerName", middleware.IsAuthenticated, DeleteDeployment)
+// registerTokenRoutes exposes personal API token management. Tokens cannot
+// mint or revoke other tokens; that needs an interactive session.
+func registerTokenRoutes(app *fiber.App) {
+	tokens := app.Group("/tokens", middleware.IsAuthenticated, middleware.RequireSession)
+	tokens.Post("/", CreateToken)
+	tokens.Get("/", ListTokens)
+	tokens.Delete("/:id", RevokeToken)
 }
 
-func healthCheck(c *fiber.Ctx) error {
-	return c.SendString("OK")
+// registerOrgRoutes exposes organizations, members and invites. Membership
+// changes need an interactive session; API tokens may only list projects.
+func registerOrgRoutes(app *fiber.App) {
+	app.Post("/orgs", middleware.IsAuthenticated, middleware.RequireSession, CreateOrg)
+	app.Get("/orgs", middleware.IsAuthenticated, middleware.RequireSession, ListOrgs)
+	app.Post("/invites/accept", middleware.IsAuthenticated, middleware.RequireSession, AcceptInvite)
+
+	app.Get("/orgs/:orgId/projects", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsRead), middleware.RequireOrgPermission(models.PermProjectsRead), GetOrgProjects)
+	app.Get("/orgs/:orgId/usage", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsRead), middleware.RequireOrgPermission(models.PermProjectsRead), GetOrgUsage)
+
+	// Not a Group with middleware: that would also match /orgs/:orgId/projects
+	// above and shut API tokens out of it.
+	session := func(perm string, h fiber.Handler) []fiber.Handler {
+		return []fiber.Handler{middleware.IsAuthenticated, middleware.RequireSession, middleware.RequireOrgPermission(perm), h}
+	}
+	app.Get("/orgs/:orgId", session("", GetOrg)...)
+	app.Patch("/orgs/:orgId", session(models.PermOrgSettings, UpdateOrgSettings)...)
+	app.Get("/orgs/:orgId/members", session("", ListOrgMembers)...)
+	app.Patch("/orgs/:orgId/members/:userId", session(models.PermMembersManage, UpdateOrgMember)...)
+	app.Delete("/orgs/:orgId/members/:userId", session("", RemoveOrgMember)...)
+	app.Post("/orgs/:orgId/invites", session(models.PermMembersManage, CreateInvite)...)
+	app.Get("/orgs/:orgId/invites", session(models.PermMembersManage, ListInvites)...)
+	app.Delete("/orgs/:orgId/invites/:inviteId", session(models.PermMembersManage, DeleteInvite)...)
+}
+
+// registerProjectRoutes exposes project routes. Every route addressing a
+// single project goes through RequireProjectPermission or
+// RequireContainerPermission; RequireScope additionally limits API tokens.
+func registerProjectRoutes(app *fiber.App) {
+	app.Post("/projects/submit", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsDeploy), middleware.RateLimitByUser(ratelimit.Deploy), HandleRepoSubmit)
+	app.Get("/projects", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsRead), GetUserProjects)
+	app.Get("/me/usage", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsRead), GetMyUsage)
+	app.Get("/projects/:id", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsRead), middleware.RequireProjectPermission(models.PermProjectsRead), GetProject)
+	app.Patch("/projects/:id", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsDeploy), middleware.RequireProjectPermission(models.PermProjectsDeploy), UpdateProject)
+	app.Post("/projects/:id/redeploy", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsDeploy), middleware.RateLimitByUser(ratelimit.Deploy), middleware.RequireProjectPermission(models.PermProjectsDeploy), RedeployProject)
+	app.Post("/projects/:id/stop", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsDeploy), middleware.RequireProjectPermission(models.PermProjectsDeploy), StopProject)
+	app.Post("/projects/:id/start", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsDeploy), middleware.RequireProjectPermission(models.PermProjectsDeploy), StartProject)
+	app.Get("/projects/:id/logs", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsRead), middleware.RequireProjectPermission(models.PermProjectsRead), GetProjectLogs)
+	app.Delete("/projects/:containerName", middleware.IsAuthenticated, middleware.RequireScope(models.ScopeProjectsDelete), middleware.RequireContainerPermission(models.PermProjectsDelete), DeleteDeployment)
 }
 
 func redirectLegacyStatic(c *fiber.Ctx) error {
@@ -0,0 +1,166 @@
+// internal/api/sessions.go
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// refreshCookieName carries the refresh token for browser flows (GitHub OAuth)
+// that cannot receive it in a JSON body.
+const refreshCookieName = "refresh_token"
+
+// issueSession signs a new access token and stores a new refresh token for
+// userID. An empty familyID starts a new family (a fresh login); rotation
+// passes the family of the token being replaced.
+func issueSession(ctx context.Context, userID primitive.ObjectID, email, familyID string) (string, string, error) {
+	accessToken, err := utils.GenerateJWT(userID.Hex(), email)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	if familyID == "" {
+		familyID = utils.GenerateRandomID()
+	}
+	now := time.Now()
+	if err := db.SaveRefreshToken(ctx, &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(utils.RefreshTokenExpiration()),
+		CreatedAt: now,
+	}); err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+func setRefreshCookie(c *fiber.Ctx, refreshToken string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
+		Path:     "/auth",
+		Expires:  time.Now().Add(utils.RefreshTokenExpiration()),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func clearRefreshCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshCookieName,
+		Value:    "",
+		Path:     "/auth",
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// refreshTokenFromRequest reads the refresh token from the JSON body, falling
+// back to the cookie. fromCookie tells the caller which transport was used.
+func refreshTokenFromRequest(c *fiber.Ctx) (token string, fromCookie bool) {
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if len(c.Body()) > 0 {
+		_ = c.BodyParser(&body)
+	}
+	if body.RefreshToken != "" {
+		return body.RefreshToken, false
+	}
+	return c.Cookies(refreshCookieName), true
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is single-use: presenting it again after
+// rotation is treated as theft and revokes the whole token family.
+func RefreshSession(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	raw, fromCookie := refreshTokenFromRequest(c)
+	if raw == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Refresh token required"})
+	}
+
+	stored, err := db.FindRefreshToken(ctx, utils.HashToken(raw))
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+	log := logger.With(c, "user_id", stored.UserID.Hex())
+
+	if stored.RevokedAt != nil {
+		log.Warn("refresh token reuse detected, revoking family", "family_id", stored.FamilyID)
+		if err := db.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			log.Error("failed to revoke refresh token family", "error", err)
+		}
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token expired"})
+	}
+
+	rotated, err := db.RevokeRefreshToken(ctx, stored.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate refresh token"})
+	}
+	if !rotated {
+		// Lost a race with another refresh using the same token.
+		_ = db.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	user, err := db.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	token, refreshToken, err := issueSession(ctx, user.ID, user.Email, stored.FamilyID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+	if fromCookie {
+		setRefreshCookie(c, refreshToken)
+	}
+	return c.JSON(fiber.Map{"token": token, "refreshToken": refreshToken})
+}
+
+// Logout revokes the access token used for this request and, when a refresh
+// token is supplied (body or cookie), the refresh token family it belongs to.
+func Logout(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	claims := c.Locals("user").(*utils.Claims)
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	if err := db.RevokeAccessToken(ctx, claims.ID, expiresAt); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke token"})
+	}
+
+	if raw, _ := refreshTokenFromRequest(c); raw != "" {
+		stored, err := db.FindRefreshToken(ctx, utils.HashToken(raw))
+		if err == nil && stored.UserID.Hex() == claims.UserID {
+			if err := db.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke refresh token"})
+			}
+		}
+	}
+	clearRefreshCookie(c)
+
+	return c.JSON(fiber.Map{"message": "Logged out"})
+}
@@ -0,0 +1,83 @@
+// internal/api/sso.go
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/gitprovider"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/sso"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SSOLogin redirects the user to the OpenID Connect provider. Like
+// OAuthLogin, ?link=<ticket> links the SSO account to an existing user.
+func SSOLogin(c *fiber.Ctx) error {
+	if !sso.Enabled() {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "SSO is not configured"})
+	}
+	flow, err := startOAuthFlow(c, c.Query("link"))
+	if err != nil {
+		logger.FromCtx(c).Warn("failed to start SSO flow", "error", err)
+		return oauthErrorRedirect(c, "invalid_request")
+	}
+	authURL, err := sso.AuthURL(c.UserContext(), flow.State, flow.Nonce, flow.PKCEChallenge())
+	if err != nil {
+		logger.FromCtx(c).Error("SSO discovery failed", "error", err)
+		return oauthErrorRedirect(c, "provider_error")
+	}
+	return c.Redirect(authURL, fiber.StatusTemporaryRedirect)
+}
+
+// SSOCallback verifies the IdP's response and signs the user in through the
+// same rules as the Git provider logins.
+func SSOCallback(c *fiber.Ctx) error {
+	if !sso.Enabled() {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "SSO is not configured"})
+	}
+	log := logger.With(c, "provider", sso.ProviderName)
+
+	flow, err := finishOAuthFlow(c)
+	if err != nil {
+		log.Warn("SSO state check failed", "error", err)
+		return oauthErrorRedirect(c, "invalid_state")
+	}
+	if c.Query("error") != "" {
+		return oauthErrorRedirect(c, "access_denied")
+	}
+	code := c.Query("code")
+	if code == "" {
+		return oauthErrorRedirect(c, "invalid_request")
+	}
+
+	identity, err := sso.Exchange(c.UserContext(), code, flow.Verifier, flow.Nonce)
+	if errors.Is(err, sso.ErrDomainNotAllowed) {
+		log.Warn("SSO login rejected by domain allowlist")
+		return oauthErrorRedirect(c, "domain_not_allowed")
+	}
+	if err != nil {
+		log.Error("SSO login failed", "error", err)
+		return oauthErrorRedirect(c, "provider_error")
+	}
+
+	return completeExternalLogin(c, sso.ProviderName, &gitprovider.Identity{
+		ID:    identity.Subject,
+		Login: identity.Login,
+		Email: identity.Email,
+	}, flow.LinkUserID)
+}
+
+// LoginProviders lists the external login options the frontend should offer.
+func LoginProviders(c *fiber.Ctx) error {
+	providers := []string{}
+	for _, name := range []string{"github", "gitlab", "bitbucket"} {
+		if p, ok := gitprovider.Get(name); ok && p.OAuthConfigured() {
+			providers = append(providers, name)
+		}
+	}
+	if sso.Enabled() {
+		providers = append(providers, sso.ProviderName)
+	}
+	return c.JSON(fiber.Map{"providers": providers})
+}
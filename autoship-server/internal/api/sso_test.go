@@ -0,0 +1,370 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/sso"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	testClientID     = "autoship-test"
+	testClientSecret = "test-secret"
+	testRedirectURI  = "http://autoship.test/auth/sso/callback"
+)
+
+// testIssuer is a minimal OpenID Connect provider: discovery, JWKS, an
+// authorization endpoint that approves every request and a token endpoint
+// that checks the PKCE verifier before issuing an RS256 ID token.
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu      sync.Mutex
+	subject string
+	email   string
+	nonce   string // overrides the requested nonce when set
+	codes   map[string]authRequest
+}
+
+type authRequest struct {
+	nonce, challenge string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{key: key, codes: map[string]authRequest{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                iss.URL,
+			"authorization_endpoint":                iss.URL + "/authorize",
+			"token_endpoint":                        iss.URL + "/token",
+			"jwks_uri":                              iss.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != testClientID || q.Get("redirect_uri") != testRedirectURI || q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad authorization request", http.StatusBadRequest)
+			return
+		}
+		code := utils.GenerateRandomID()
+		iss.mu.Lock()
+		iss.codes[code] = authRequest{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+		iss.mu.Unlock()
+		http.Redirect(w, r, testRedirectURI+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != testClientID || secret != testClientSecret {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		iss.mu.Lock()
+		req, ok := iss.codes[r.PostFormValue("code")]
+		delete(iss.codes, r.PostFormValue("code"))
+		subject, email, nonce := iss.subject, iss.email, iss.nonce
+		iss.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		if nonce == "" {
+			nonce = req.nonce
+		}
+
+		now := time.Now()
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            iss.URL,
+			"sub":            subject,
+			"aud":            testClientID,
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Minute).Unix(),
+			"nonce":          nonce,
+			"email":          email,
+			"email_verified": true,
+		})
+		idToken.Header["kid"] = "test"
+		signed, err := idToken.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]any{"access_token": "opaque", "token_type": "Bearer", "expires_in": 60, "id_token": signed})
+	})
+
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// signInAs makes the issuer vouch for subject with email on the next logins.
+func (iss *testIssuer) signInAs(subject, email string) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.subject, iss.email, iss.nonce = subject, email, ""
+}
+
+// ssoApp configures SSO against a fresh test issuer and returns the routes.
+func ssoApp(t *testing.T) (*fiber.App, *testIssuer) {
+	t.Helper()
+	app := testApp(t)
+	iss := newTestIssuer(t)
+	t.Setenv("OIDC_ISSUER_URL", iss.URL)
+	t.Setenv("OIDC_CLIENT_ID", testClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", testClientSecret)
+	t.Setenv("OIDC_REDIRECT_URI", testRedirectURI)
+	t.Setenv("FRONTEND_URL", "http://frontend.test")
+	if err := sso.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv("OIDC_ISSUER_URL")
+		_ = sso.Init()
+	})
+	return app, iss
+}
+
+// ssoLogin runs the browser's side of a login: GET startPath, follow the
+// redirect through the issuer and return the callback URL and flow cookie.
+func ssoLogin(t *testing.T, app *fiber.App, startPath string) (callback *url.URL, flowCookie *http.Cookie) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", startPath, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusTemporaryRedirect {
+		t.Fatalf("GET %s = %d, want %d", startPath, resp.StatusCode, fiber.StatusTemporaryRedirect)
+	}
+	for _, c := range resp.Cookies() {
+		if c.Name == oauthFlowCookie {
+			flowCookie = c
+		}
+	}
+	if flowCookie == nil {
+		t.Fatalf("GET %s set no %s cookie", startPath, oauthFlowCookie)
+	}
+
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	authResp, err := noFollow.Get(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authResp.Body.Close()
+	if authResp.StatusCode != http.StatusFound {
+		t.Fatalf("issuer authorization = %d, want %d", authResp.StatusCode, http.StatusFound)
+	}
+	callback, err = url.Parse(authResp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback, flowCookie
+}
+
+// ssoCallback delivers the issuer's redirect to the server and returns where
+// the server sends the browser next.
+func ssoCallback(t *testing.T, app *fiber.App, callback *url.URL, flowCookie *http.Cookie) *url.URL {
+	t.Helper()
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	if flowCookie != nil {
+		req.AddCookie(flowCookie)
+	}
+	resp, err := app.Test(req, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("GET %s = %d, want %d", callback.Path, resp.StatusCode, fiber.StatusFound)
+	}
+	next, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return next
+}
+
+func wantRedirect(t *testing.T, got *url.URL, path, query, value string) {
+	t.Helper()
+	if got.Path != path || got.Query().Get(query) != value {
+		t.Fatalf("redirected to %s, want %s?%s=%s", got, path, query, value)
+	}
+}
+
+func cleanupSSOUser(t *testing.T, subject string) {
+	t.Cleanup(func() {
+		ctx := context.Background()
+		if user, err := db.GetUserByIdentity(ctx, sso.ProviderName, subject); err == nil {
+			_, _ = db.GetCollection("refresh_tokens").DeleteMany(ctx, bson.M{"user_id": user.ID})
+			_, _ = db.GetCollection("users").DeleteOne(ctx, bson.M{"_id": user.ID})
+		}
+	})
+}
+
+func TestSSOLoginCreatesAndSignsInUser(t *testing.T) {
+	app, iss := ssoApp(t)
+	ctx := context.Background()
+
+	subject := "sub-" + primitive.NewObjectID().Hex()
+	email := subject + "@example.com"
+	iss.signInAs(subject, strings.ToUpper(email))
+	cleanupSSOUser(t, subject)
+
+	callback, cookie := ssoLogin(t, app, "/auth/sso")
+	next := ssoCallback(t, app, callback, cookie)
+	if next.Path != "/dashboard" || next.Query().Get("token") == "" {
+		t.Fatalf("first login redirected to %s, want /dashboard?token=...", next)
+	}
+	user, err := db.GetUserByIdentity(ctx, sso.ProviderName, subject)
+	if err != nil {
+		t.Fatalf("no user linked to the SSO subject: %v", err)
+	}
+	if user.Email != email || !user.EmailVerified {
+		t.Errorf("created user email = %q verified = %v, want %q verified", user.Email, user.EmailVerified, email)
+	}
+
+	// A returning user signs in to the same account.
+	callback, cookie = ssoLogin(t, app, "/auth/sso")
+	next = ssoCallback(t, app, callback, cookie)
+	claims, err := utils.VerifyJWT(next.Query().Get("token"))
+	if err != nil {
+		t.Fatalf("second login token: %v", err)
+	}
+	if claims.UserID != user.ID.Hex() {
+		t.Errorf("second login signed in as %s, want %s", claims.UserID, user.ID.Hex())
+	}
+}
+
+func TestSSOCallbackRejectsBadState(t *testing.T) {
+	app, iss := ssoApp(t)
+	subject := "sub-" + primitive.NewObjectID().Hex()
+	iss.signInAs(subject, subject+"@example.com")
+	cleanupSSOUser(t, subject)
+
+	callback, cookie := ssoLogin(t, app, "/auth/sso")
+
+	tampered := *callback
+	q := tampered.Query()
+	q.Set("state", "forged")
+	tampered.RawQuery = q.Encode()
+	wantRedirect(t, ssoCallback(t, app, &tampered, cookie), "/login", "error", "invalid_state")
+	wantRedirect(t, ssoCallback(t, app, callback, nil), "/login", "error", "invalid_state")
+
+	// A flow cookie from another login does not match this state.
+	_, otherCookie := ssoLogin(t, app, "/auth/sso")
+	wantRedirect(t, ssoCallback(t, app, callback, otherCookie), "/login", "error", "invalid_state")
+
+	if _, err := db.GetUserByIdentity(context.Background(), sso.ProviderName, subject); err == nil {
+		t.Error("user created despite the state mismatch")
+	}
+}
+
+func TestSSOCallbackRejectsNonceMismatch(t *testing.T) {
+	app, iss := ssoApp(t)
+	subject := "sub-" + primitive.NewObjectID().Hex()
+	iss.signInAs(subject, subject+"@example.com")
+	iss.mu.Lock()
+	iss.nonce = "replayed"
+	iss.mu.Unlock()
+	cleanupSSOUser(t, subject)
+
+	callback, cookie := ssoLogin(t, app, "/auth/sso")
+	wantRedirect(t, ssoCallback(t, app, callback, cookie), "/login", "error", "provider_error")
+
+	if _, err := db.GetUserByIdentity(context.Background(), sso.ProviderName, subject); err == nil {
+		t.Error("user created from an ID token with the wrong nonce")
+	}
+}
+
+func TestSSOLinksExistingAccount(t *testing.T) {
+	app, iss := ssoApp(t)
+	ctx := context.Background()
+
+	user, token := createTestUser(t)
+	subject := "sub-" + primitive.NewObjectID().Hex()
+
+	// An SSO login with the email of a password account must not take it over.
+	if _, err := db.GetCollection("users").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"password": "hash"}}); err != nil {
+		t.Fatal(err)
+	}
+	iss.signInAs(subject, user.Email)
+	callback, cookie := ssoLogin(t, app, "/auth/sso")
+	wantRedirect(t, ssoCallback(t, app, callback, cookie), "/login", "error", "account_exists")
+
+	status, body := doRequest(t, app, "POST", "/auth/link?provider=sso", token)
+	if status != fiber.StatusOK {
+		t.Fatalf("POST /auth/link = %d %s, want 200", status, body)
+	}
+	var ticket struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(body, &ticket); err != nil {
+		t.Fatal(err)
+	}
+	start, err := url.Parse(ticket.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback, cookie = ssoLogin(t, app, start.RequestURI())
+	wantRedirect(t, ssoCallback(t, app, callback, cookie), "/dashboard/settings", "linked", sso.ProviderName)
+
+	linked, err := db.GetUserByIdentity(ctx, sso.ProviderName, subject)
+	if err != nil || linked.ID != user.ID {
+		t.Fatalf("SSO subject linked to %v (%v), want user %s", linked, err, user.ID.Hex())
+	}
+
+	// The same SSO account cannot be linked to a second user.
+	_, otherToken := createTestUser(t)
+	status, body = doRequest(t, app, "POST", "/auth/link?provider=sso", otherToken)
+	if status != fiber.StatusOK {
+		t.Fatalf("POST /auth/link = %d %s, want 200", status, body)
+	}
+	if err := json.Unmarshal(body, &ticket); err != nil {
+		t.Fatal(err)
+	}
+	start, _ = url.Parse(ticket.URL)
+	callback, cookie = ssoLogin(t, app, start.RequestURI())
+	wantRedirect(t, ssoCallback(t, app, callback, cookie), "/login", "error", "account_already_linked")
+}
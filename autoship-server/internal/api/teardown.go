@@ -0,0 +1,151 @@
+// internal/api/teardown.go
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/middleware"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ports"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/services"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// teardownStep releases one resource of a deleted project. run must succeed
+// when the resource is already gone, so a failed deletion can be retried.
+type teardownStep struct {
+	name string
+	run  func(ctx context.Context) error
+	// blocking steps stop the teardown when they fail: nothing the
+	// container still uses may be released before it is gone.
+	blocking bool
+}
+
+// teardownResult reports one step of a DELETE request.
+type teardownResult struct {
+	Step   string `json:"step"`
+	Status string `json:"status"` // "done", "already_done", "failed" or "pending"
+	Error  string `json:"error,omitempty"`
+}
+
+// teardownSteps lists what deleting p releases, in order.
+func teardownSteps(p *models.Project) []teardownStep {
+	var steps []teardownStep
+	if p.ContainerName != "" {
+		name := p.Container()
+		steps = append(steps,
+			teardownStep{name: "container", blocking: true, run: func(ctx context.Context) error {
+				return services.DeleteProject(name)
+			}},
+			teardownStep{name: "image", run: func(ctx context.Context) error {
+				return services.RemoveImage(ctx, name)
+			}},
+			// Closed before the lease is released, so a new deployment
+			// that picks the port up cannot have its rule revoked.
+			teardownStep{name: "firewall", run: func(ctx context.Context) error {
+				return revokeHostPort(ctx, p.HostPort)
+			}},
+			teardownStep{name: "port", run: func(ctx context.Context) error {
+				return ports.Release(ctx, p.HostPort, name)
+			}},
+			teardownStep{name: "proxy", run: func(ctx context.Context) error {
+				subdomain := projectSubdomain(p)
+				if subdomain == "" {
+					return fmt.Errorf("project has no subdomain to remove")
+				}
+				return unregisterWithAgent(ctx, utils.GenerateRandomID(), subdomain)
+			}},
+			teardownStep{name: "build_logs", run: func(ctx context.Context) error {
+				return services.RemoveBuildLogs(name)
+			}},
+		)
+	}
+	if p.ProjectType == "static" {
+		steps = append(steps, teardownStep{name: "storage", run: func(ctx context.Context) error {
+			return deleteStaticSite(ctx, p)
+		}})
+	}
+	return steps
+}
+
+// revokeHostPort closes the cloud firewall rule of a container's host port.
+// Private network mode opens no rules; ones left from before switching to it
+// are cleaned up by cmd/reconcile-firewall.
+func revokeHostPort(ctx context.Context, port int) error {
+	if port == 0 || services.PrivateNetwork() {
+		return nil
+	}
+	return cloud.Get().RevokePort(ctx, port)
+}
+
+// DeleteDeployment deletes a project and everything it holds: container,
+// image, firewall rule, port, proxy configuration (nginx, DNS, TLS) and build
+// logs for dynamic projects, the uploaded files for static ones.
+// Access has already been checked by middleware.RequireContainerPermission.
+//
+// The project is marked deleting and every step's outcome is stored on it, so
+// a request that fails part way answers 500 with the per-step results and can
+// simply be repeated: steps that already succeeded are skipped. The project
+// document is removed once every step has succeeded.
+func DeleteDeployment(c *fiber.Ctx) error {
+	project := middleware.ProjectFromCtx(c)
+	log := logger.With(c, "container", project.Container())
+	ctx := logger.NewContext(c.UserContext(), log)
+
+	if !project.Deleting() {
+		if err := db.MarkProjectDeleting(ctx, project.ID); err != nil {
+			log.Error("failed to mark project deleting", "error", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete deployment")
+		}
+	}
+
+	results := []teardownResult{}
+	failed, blocked := false, false
+	for _, step := range teardownSteps(project) {
+		switch {
+		case blocked:
+			results = append(results, teardownResult{Step: step.name, Status: "pending"})
+			continue
+		case project.Teardown[step.name].Done:
+			results = append(results, teardownResult{Step: step.name, Status: "already_done"})
+			continue
+		}
+
+		err := step.run(ctx)
+		if recErr := db.RecordTeardownStep(ctx, project.ID, step.name, err); recErr != nil {
+			log.Error("failed to record teardown step", "step", step.name, "error", recErr)
+		}
+		if err != nil {
+			log.Error("teardown step failed", "step", step.name, "error", err)
+			results = append(results, teardownResult{Step: step.name, Status: "failed", Error: err.Error()})
+			failed = true
+			blocked = step.blocking
+			continue
+		}
+		results = append(results, teardownResult{Step: step.name, Status: "done"})
+	}
+
+	if !failed {
+		if err := db.DeleteProjectByID(ctx, project.ID); err != nil {
+			log.Error("failed to delete project from DB", "error", err)
+			results = append(results, teardownResult{Step: "record", Status: "failed", Error: "failed to delete project record"})
+			failed = true
+		} else {
+			results = append(results, teardownResult{Step: "record", Status: "done"})
+		}
+	}
+	if failed {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Deployment was only partially deleted; repeat the request to retry the failed steps",
+			"steps": results,
+		})
+	}
+
+	log.Info("project deleted")
+	return c.JSON(fiber.Map{"message": "Deployment deleted successfully", "steps": results})
+}
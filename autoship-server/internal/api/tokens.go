@@ -0,0 +1,126 @@
+// internal/api/tokens.go
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxTokenNameLength bounds the user-chosen label of an API token.
+const maxTokenNameLength = 100
+
+// CreateTokenRequest is the body of POST /tokens. ExpiresInDays of 0 creates a
+// token that never expires.
+type CreateTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty"`
+}
+
+// CreateToken issues a personal API token. The token value is returned only in
+// this response.
+func CreateToken(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	var req CreateTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxTokenNameLength {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "name is required (max 100 characters)"})
+	}
+	if len(req.Scopes) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "at least one scope is required", "validScopes": models.APITokenScopes})
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.APITokenScopes, scope) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "unknown scope " + scope, "validScopes": models.APITokenScopes})
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "expiresInDays must not be negative"})
+	}
+
+	raw, err := utils.GenerateAPIToken()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+
+	now := time.Now()
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    raw[:len(utils.APITokenPrefix)+6],
+		TokenHash: utils.HashToken(raw),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		CreatedAt: now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := db.SaveAPIToken(c.UserContext(), token); err != nil {
+		logger.FromCtx(c).Error("failed to save API token", "error", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating token"})
+	}
+
+	logger.FromCtx(c).Info("API token created", "api_token_id", token.ID.Hex(), "scopes", token.Scopes)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "Token created. Copy it now, it will not be shown again.",
+		"token":   raw,
+		"details": token,
+	})
+}
+
+// ListTokens returns the caller's API tokens without their values.
+func ListTokens(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+
+	tokens, err := db.ListAPITokens(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch tokens"})
+	}
+	return c.JSON(tokens)
+}
+
+// RevokeToken deletes one of the caller's API tokens.
+func RevokeToken(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	tokenID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid token id"})
+	}
+
+	deleted, err := db.DeleteAPIToken(c.UserContext(), tokenID, userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke token"})
+	}
+	if !deleted {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Token not found"})
+	}
+
+	logger.FromCtx(c).Info("API token revoked", "api_token_id", tokenID.Hex())
+	return c.JSON(fiber.Map{"message": "Token revoked"})
+}
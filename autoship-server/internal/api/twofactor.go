@@ -0,0 +1,284 @@
+// internal/api/twofactor.go
+package api
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultTOTPIssuer names the account in authenticator apps when TOTP_ISSUER
+// is unset.
+const defaultTOTPIssuer = "Auto-Ship"
+
+// secondFactor is the body of every request that has to prove possession of
+// the second factor: a current TOTP code or one unused recovery code.
+type secondFactor struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// verifySecondFactor checks f for user, burning the TOTP step or recovery
+// code it used. Failures count towards the user's 2FA lockout; while it is
+// locked no code is checked and wait is how long remains.
+func verifySecondFactor(c *fiber.Ctx, user *models.User, f secondFactor) (ok bool, wait time.Duration, err error) {
+	if !user.TOTPEnabled {
+		return false, 0, nil
+	}
+	lockKey := twoFactorLockKey(user.ID.Hex())
+	if w := lockedFor(c, lockKey); w > 0 {
+		return false, w, nil
+	}
+
+	ctx := c.UserContext()
+	if f.RecoveryCode != "" {
+		ok, err = db.ConsumeRecoveryCode(ctx, user.ID, utils.HashRecoveryCode(f.RecoveryCode))
+	} else if step, valid := utils.ValidateTOTP(user.TOTPSecret, f.Code, time.Now()); valid {
+		ok, err = db.UseTOTPStep(ctx, user.ID, step)
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	if ok {
+		clearAuthFailures(c, lockKey)
+	} else {
+		recordAuthFailure(c, lockKey)
+	}
+	return ok, 0, nil
+}
+
+// currentUser loads the authenticated caller.
+func currentUser(c *fiber.Ctx) (*models.User, error) {
+	userID, err := callerID(c)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetUserByID(c.UserContext(), userID)
+}
+
+// mfaChallengeResponse answers a login whose first factor passed for a user
+// with 2FA enabled: instead of a session the client gets a challenge token to
+// redeem at POST /auth/login/2fa.
+func mfaChallengeResponse(c *fiber.Ctx, userID primitive.ObjectID) error {
+	challenge, err := utils.GenerateMFAChallenge(userID.Hex())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication required",
+		"mfaRequired":    true,
+		"challengeToken": challenge,
+		"expiresIn":      int(utils.MFAChallengeTTL / time.Second),
+	})
+}
+
+// LoginTwoFactor completes a two-step login with the challenge token from
+// Login (or a browser login) and a TOTP or recovery code.
+func LoginTwoFactor(c *fiber.Ctx) error {
+	var req struct {
+		ChallengeToken string `json:"challengeToken"`
+		secondFactor
+	}
+	if err := c.BodyParser(&req); err != nil || req.ChallengeToken == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "challengeToken and code are required"})
+	}
+	ctx := c.UserContext()
+
+	uid, err := utils.VerifyMFAChallenge(req.ChallengeToken)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge"})
+	}
+	userID, err := primitive.ObjectIDFromHex(uid)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge"})
+	}
+	user, err := db.GetUserByID(ctx, userID)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired challenge"})
+	}
+
+	ok, wait, err := verifySecondFactor(c, user, req.secondFactor)
+	if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		logger.FromCtx(c).Warn("2FA login failed", "user_id", uid)
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid code"})
+	}
+	if req.RecoveryCode != "" {
+		logger.FromCtx(c).Info("recovery code used", "user_id", uid)
+	}
+
+	token, refreshToken, err := issueSession(ctx, user.ID, user.Email, "")
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+	return c.JSON(fiber.Map{"message": "Login successful", "token": token, "refreshToken": refreshToken})
+}
+
+// TwoFactorStatus reports whether the caller has 2FA enabled and how many
+// recovery codes they have left.
+func TwoFactorStatus(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	return c.JSON(fiber.Map{"enabled": user.TOTPEnabled, "recoveryCodesRemaining": len(user.RecoveryCodes)})
+}
+
+// StartTwoFactorEnrollment generates a new TOTP secret for the caller and
+// returns it with its otpauth:// provisioning URI, which the frontend shows
+// as a QR code. 2FA is only switched on by ConfirmTwoFactorEnrollment.
+func StartTwoFactorEnrollment(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	if user.TOTPEnabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start enrolment"})
+	}
+	stored, err := db.SetPendingTOTP(c.UserContext(), user.ID, secret)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start enrolment"})
+	}
+	if !stored {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+	return c.JSON(fiber.Map{
+		"secret":          secret,
+		"provisioningUri": utils.TOTPProvisioningURI(secret, user.Email, issuer),
+	})
+}
+
+// ConfirmTwoFactorEnrollment enables 2FA once the caller proves their
+// authenticator app produces valid codes, and returns the recovery codes.
+// They are shown only this once.
+func ConfirmTwoFactorEnrollment(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	if user.TOTPEnabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if user.TOTPSecret == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Start enrolment first"})
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid code"})
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable two-factor authentication"})
+	}
+	enabled, err := db.EnableTOTP(c.UserContext(), user.ID, user.TOTPSecret, step, hashes)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to enable two-factor authentication"})
+	}
+	if !enabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Enrolment changed, start again"})
+	}
+
+	logger.FromCtx(c).Info("2FA enabled", "user_id", user.ID.Hex())
+	return c.JSON(fiber.Map{"message": "Two-factor authentication enabled", "recoveryCodes": codes})
+}
+
+// DisableTwoFactor turns 2FA off. It needs a current code or a recovery code,
+// so a stolen session alone cannot remove the second factor.
+func DisableTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	if !user.TOTPEnabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	var req secondFactor
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	ok, wait, err := verifySecondFactor(c, user, req)
+	if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid code"})
+	}
+	if err := db.DisableTOTP(c.UserContext(), user.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable two-factor authentication"})
+	}
+
+	logger.FromCtx(c).Info("2FA disabled", "user_id", user.ID.Hex())
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes. It needs a
+// current TOTP code.
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	if !user.TOTPEnabled {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	ok, wait, err := verifySecondFactor(c, user, secondFactor{Code: req.Code})
+	if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify code"})
+	}
+	if !ok {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid code"})
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+	if err := db.ReplaceRecoveryCodes(c.UserContext(), user.ID, hashes); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+	return c.JSON(fiber.Map{"recoveryCodes": codes})
+}
@@ -0,0 +1,63 @@
+// internal/api/usage.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/middleware"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/quota"
+	"github.com/gofiber/fiber/v2"
+)
+
+// quotaError answers a request rejected by a quota check: 429 for limits that
+// free up by themselves (concurrent builds), 403 for the rest.
+func quotaError(c *fiber.Ctx, err error) error {
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		logger.FromCtx(c).Error("quota check failed", "error", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check quota"})
+	}
+
+	logger.FromCtx(c).Info("quota exceeded", "resource", exceeded.Resource, "limit", exceeded.Limit, "used", exceeded.Used)
+	status, msg := http.StatusForbidden, fmt.Sprintf("Quota exceeded: %s limit is %d (using %d)", exceeded.Resource, exceeded.Limit, exceeded.Used)
+	if exceeded.Transient() {
+		status, msg = http.StatusTooManyRequests, "Too many builds in progress, wait for one to finish"
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":    msg,
+		"resource": exceeded.Resource,
+		"limit":    exceeded.Limit,
+		"used":     exceeded.Used,
+	})
+}
+
+// usageResponse reports an account's plan, limits and current usage.
+func usageResponse(c *fiber.Ctx, account quota.Account) error {
+	plan, limits, err := quota.Limits(c.UserContext(), account)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch usage"})
+	}
+	usage, err := quota.Usage(c.UserContext(), account)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch usage"})
+	}
+	return c.JSON(fiber.Map{"plan": plan, "limits": limits, "usage": usage})
+}
+
+// GetMyUsage reports the quota usage of the caller's personal projects.
+func GetMyUsage(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user"})
+	}
+	return usageResponse(c, quota.Account{UserID: userID})
+}
+
+// GetOrgUsage reports the quota usage of an organization's projects.
+func GetOrgUsage(c *fiber.Ctx) error {
+	m := middleware.MembershipFromCtx(c)
+	return usageResponse(c, quota.Account{UserID: m.UserID, OrgID: m.OrgID})
+}
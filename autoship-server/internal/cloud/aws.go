@@ -3,17 +3,23 @@ package cloud
 import (
 	"context"
 	"fmt"
+	neturl "net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/tracing"
 	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 
 	awsv1 "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // awsProvider hosts static sites on S3 and opens container ports in an EC2
@@ -30,36 +36,110 @@ type awsProvider struct {
 // newAWSProvider loads AWS config and validates the env vars required for static
 // hosting. The security-group id is optional here and only enforced when a
 // dynamic project actually needs a port opened (see AuthorizePort).
+//
+// S3_ENDPOINT points static hosting at an S3-compatible store (MinIO, R2,
+// Wasabi, ...), S3_FORCE_PATH_STYLE addresses buckets as <endpoint>/<bucket>
+// instead of <bucket>.<endpoint>, and S3_PUBLIC_BASE_URL overrides the base
+// of the returned links (e.g. a CDN or the store's public bucket URL).
 func newAWSProvider() (Provider, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
-		return nil, fmt.Errorf("unable to load AWS config: %w", err)
-	}
-
 	bucket := os.Getenv("S3_BUCKET_NAME")
 	if bucket == "" {
 		return nil, fmt.Errorf("S3_BUCKET_NAME not set")
 	}
+	endpoint := strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/")
 	region := os.Getenv("AWS_REGION")
 	if region == "" {
-		return nil, fmt.Errorf("AWS_REGION not set")
+		if endpoint == "" {
+			return nil, fmt.Errorf("AWS_REGION not set")
+		}
+		// Most S3-compatible stores ignore the region but the SDK needs one
+		// to sign requests.
+		region = "us-east-1"
+	}
+	pathStyle := false
+	if v := os.Getenv("S3_FORCE_PATH_STYLE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3_FORCE_PATH_STYLE %q: %w", v, err)
+		}
+		pathStyle = b
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	websiteURL := os.Getenv("S3_PUBLIC_BASE_URL")
+	if websiteURL == "" {
+		websiteURL, err = s3WebsiteURL(endpoint, bucket, region, pathStyle)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = pathStyle
+		if endpoint != "" {
+			o.BaseEndpoint = awsv2.String(endpoint)
+			// Not every S3-compatible store accepts the checksums the SDK
+			// adds to uploads by default.
+			o.RequestChecksumCalculation = awsv2.RequestChecksumCalculationWhenRequired
+		}
+	})
+
 	return &awsProvider{
-		s3Client:   s3.NewFromConfig(cfg),
+		s3Client:   client,
 		bucket:     bucket,
-		websiteURL: fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region),
+		websiteURL: strings.TrimRight(websiteURL, "/"),
 		region:     region,
 		sgID:       os.Getenv("EC2_SECURITY_GROUP_ID"),
 	}, nil
 }
 
+// s3WebsiteURL returns the URL objects of bucket are served at: the AWS S3
+// URL, or the bucket on endpoint in path or virtual-hosted style.
+func s3WebsiteURL(endpoint, bucket, region string, pathStyle bool) (string, error) {
+	if endpoint == "" {
+		if pathStyle {
+			return fmt.Sprintf("https://s3.%s.amazonaws.com/%s", region, bucket), nil
+		}
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region), nil
+	}
+
+	u, err := neturl.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid S3_ENDPOINT %q (expected e.g. http://localhost:9000)", endpoint)
+	}
+	if pathStyle {
+		return fmt.Sprintf("%s/%s", endpoint, bucket), nil
+	}
+	u.Host = bucket + "." + u.Host
+	return u.String(), nil
+}
+
 func (a *awsProvider) Name() string { return "aws" }
 
+// CheckCredentials issues a HeadBucket on the configured bucket.
+func (a *awsProvider) CheckCredentials(ctx context.Context) error {
+	_, err := a.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: awsv2.String(a.bucket)})
+	if err != nil {
+		return fmt.Errorf("S3 HeadBucket %s failed: %w", a.bucket, err)
+	}
+	return nil
+}
+
 // UploadStaticSite uploads a folder recursively to S3 (uncompressed) and returns
 // the URL to its index.html.
-func (a *awsProvider) UploadStaticSite(localPath, keyPrefix string) (string, error) {
-	err := filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+func (a *awsProvider) UploadStaticSite(ctx context.Context, localPath, keyPrefix string) (url string, err error) {
+	ctx, span := tracing.Start(ctx, "s3.upload_static_site",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("s3.bucket", a.bucket),
+		attribute.String("s3.key_prefix", keyPrefix),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -79,15 +159,17 @@ func (a *awsProvider) UploadStaticSite(localPath, keyPrefix string) (string, err
 		}
 		defer file.Close()
 
-		_, err = a.s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
+		_, err = a.s3Client.PutObject(ctx, &s3.PutObjectInput{
 			Bucket:      awsv2.String(a.bucket),
 			Key:         awsv2.String(key),
 			Body:        file,
 			ContentType: awsv2.String(contentTypeByExtension(path)),
 		})
 		if err != nil {
+			metrics.CloudUploadErrors.WithLabelValues(a.Name()).Inc()
 			return fmt.Errorf("failed to upload file %s: %w", key, err)
 		}
+		metrics.CloudUploadBytes.WithLabelValues(a.Name()).Add(float64(info.Size()))
 		return nil
 	})
 	if err != nil {
@@ -97,36 +179,154 @@ func (a *awsProvider) UploadStaticSite(localPath, keyPrefix string) (string, err
 	return fmt.Sprintf("%s/%s/index.html", a.websiteURL, keyPrefix), nil
 }
 
+// s3DeleteBatch is the most keys a single DeleteObjects call accepts.
+const s3DeleteBatch = 1000
+
+// DeleteStaticSite lists the objects under keyPrefix and removes them with
+// DeleteObjects, up to 1000 keys per call.
+func (a *awsProvider) DeleteStaticSite(ctx context.Context, keyPrefix string) (err error) {
+	ctx, span := tracing.Start(ctx, "s3.delete_static_site",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("s3.bucket", a.bucket),
+		attribute.String("s3.key_prefix", keyPrefix),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	prefix, err := sitePrefix(keyPrefix)
+	if err != nil {
+		return err
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(a.s3Client, &s3.ListObjectsV2Input{
+		Bucket:  awsv2.String(a.bucket),
+		Prefix:  awsv2.String(prefix),
+		MaxKeys: awsv2.Int32(s3DeleteBatch),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects under %s: %w", prefix, err)
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		objects := make([]s3types.ObjectIdentifier, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			objects = append(objects, s3types.ObjectIdentifier{Key: obj.Key})
+		}
+		out, err := a.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: awsv2.String(a.bucket),
+			Delete: &s3types.Delete{Objects: objects, Quiet: awsv2.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects under %s: %w", prefix, err)
+		}
+		if len(out.Errors) > 0 {
+			first := out.Errors[0]
+			return fmt.Errorf("failed to delete %d objects under %s, first %s: %s",
+				len(out.Errors), prefix, awsv2.ToString(first.Key), awsv2.ToString(first.Message))
+		}
+	}
+	return nil
+}
+
 // AuthorizePort opens an inbound TCP rule for port in the configured EC2 security
 // group (0.0.0.0/0). A duplicate rule is treated as success.
-func (a *awsProvider) AuthorizePort(port int) error {
+func (a *awsProvider) AuthorizePort(ctx context.Context, port int) (err error) {
+	ctx, span := tracing.Start(ctx, "ec2.authorize_security_group_ingress",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("ec2.security_group_id", a.sgID),
+		attribute.Int("net.host.port", port),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	if a.sgID == "" {
 		return fmt.Errorf("EC2_SECURITY_GROUP_ID not set; cannot open port %d", port)
 	}
 
-	sess := session.Must(session.NewSession(&awsv1.Config{
-		Region: awsv1.String(a.region),
-	}))
-	svc := ec2.New(sess)
-
-	_, err := svc.AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
-		GroupId: awsv1.String(a.sgID),
-		IpPermissions: []*ec2.IpPermission{
-			{
-				IpProtocol: awsv1.String("tcp"),
-				FromPort:   awsv1.Int64(int64(port)),
-				ToPort:     awsv1.Int64(int64(port)),
-				IpRanges: []*ec2.IpRange{
-					{
-						CidrIp:      awsv1.String("0.0.0.0/0"),
-						Description: awsv1.String("Auto-opened for container hosting"),
-					},
-				},
-			},
-		},
+	_, err = a.ec2Client().AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       awsv1.String(a.sgID),
+		IpPermissions: []*ec2.IpPermission{portPermission(port, autoOpenedDescription)},
 	})
 	if err != nil && !strings.Contains(err.Error(), "InvalidPermission.Duplicate") {
 		return err
 	}
 	return nil
 }
+
+// RevokePort removes the inbound rule AuthorizePort added for port. A rule
+// that does not exist is treated as success.
+func (a *awsProvider) RevokePort(ctx context.Context, port int) (err error) {
+	ctx, span := tracing.Start(ctx, "ec2.revoke_security_group_ingress",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("ec2.security_group_id", a.sgID),
+		attribute.Int("net.host.port", port),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if a.sgID == "" {
+		return fmt.Errorf("EC2_SECURITY_GROUP_ID not set; cannot close port %d", port)
+	}
+
+	_, err = a.ec2Client().RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+		GroupId:       awsv1.String(a.sgID),
+		IpPermissions: []*ec2.IpPermission{portPermission(port, "")},
+	})
+	if err != nil && !strings.Contains(err.Error(), "InvalidPermission.NotFound") {
+		return err
+	}
+	return nil
+}
+
+// AuthorizedPorts returns the single-port 0.0.0.0/0 rules of the security
+// group that carry AuthorizePort's description.
+func (a *awsProvider) AuthorizedPorts(ctx context.Context) ([]int, error) {
+	if a.sgID == "" {
+		return nil, fmt.Errorf("EC2_SECURITY_GROUP_ID not set")
+	}
+	out, err := a.ec2Client().DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds: []*string{awsv1.String(a.sgID)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe security group %s: %w", a.sgID, err)
+	}
+
+	var ports []int
+	for _, sg := range out.SecurityGroups {
+		for _, perm := range sg.IpPermissions {
+			if awsv1.StringValue(perm.IpProtocol) != "tcp" || perm.FromPort == nil || awsv1.Int64Value(perm.FromPort) != awsv1.Int64Value(perm.ToPort) {
+				continue
+			}
+			for _, r := range perm.IpRanges {
+				if awsv1.StringValue(r.CidrIp) == "0.0.0.0/0" && awsv1.StringValue(r.Description) == autoOpenedDescription {
+					ports = append(ports, int(awsv1.Int64Value(perm.FromPort)))
+					break
+				}
+			}
+		}
+	}
+	return ports, nil
+}
+
+// ec2Client returns an EC2 client for the configured region.
+func (a *awsProvider) ec2Client() *ec2.EC2 {
+	sess := session.Must(session.NewSession(&awsv1.Config{
+		Region: awsv1.String(a.region),
+	}))
+	return ec2.New(sess)
+}
+
+// portPermission is the inbound rule opening TCP port to 0.0.0.0/0.
+func portPermission(port int, description string) *ec2.IpPermission {
+	ipRange := &ec2.IpRange{CidrIp: awsv1.String("0.0.0.0/0")}
+	if description != "" {
+		ipRange.Description = awsv1.String(description)
+	}
+	return &ec2.IpPermission{
+		IpProtocol: awsv1.String("tcp"),
+		FromPort:   awsv1.Int64(int64(port)),
+		ToPort:     awsv1.Int64(int64(port)),
+		IpRanges:   []*ec2.IpRange{ipRange},
+	}
+}
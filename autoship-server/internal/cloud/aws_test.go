@@ -0,0 +1,129 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestS3WebsiteURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoint  string
+		pathStyle bool
+		want      string
+		wantErr   bool
+	}{
+		{"aws virtual-hosted", "", false, "https://b.s3.us-east-1.amazonaws.com", false},
+		{"aws path-style", "", true, "https://s3.us-east-1.amazonaws.com/b", false},
+		{"endpoint path-style", "http://localhost:9000", true, "http://localhost:9000/b", false},
+		{"endpoint virtual-hosted", "https://acc.r2.cloudflarestorage.com", false, "https://b.acc.r2.cloudflarestorage.com", false},
+		{"endpoint with port virtual-hosted", "http://minio.internal:9000", false, "http://b.minio.internal:9000", false},
+		{"endpoint without scheme", "localhost:9000", true, "", true},
+		{"endpoint without host", "http://", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s3WebsiteURL(tt.endpoint, "b", "us-east-1", tt.pathStyle)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("s3WebsiteURL(%q, %v) error = %v, wantErr %v", tt.endpoint, tt.pathStyle, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("s3WebsiteURL(%q, %v) = %q, want %q", tt.endpoint, tt.pathStyle, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestS3StaticSiteRoundTrip uploads a site to the S3-compatible store named by
+// S3_ENDPOINT, fetches it from the returned URL and deletes it again. The
+// bucket (S3_BUCKET_NAME) must exist and allow anonymous reads, as the
+// "minio" docker compose profile sets up:
+//
+//	S3_ENDPOINT=http://localhost:9000 S3_FORCE_PATH_STYLE=true \
+//	S3_BUCKET_NAME=autoship AWS_ACCESS_KEY_ID=minioadmin \
+//	AWS_SECRET_ACCESS_KEY=minioadmin go test ./internal/cloud
+func TestS3StaticSiteRoundTrip(t *testing.T) {
+	if os.Getenv("S3_ENDPOINT") == "" {
+		t.Skip("S3_ENDPOINT not set")
+	}
+	ctx := context.Background()
+
+	p, err := newAWSProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CheckCredentials(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	site := t.TempDir()
+	const index = "<h1>round trip</h1>"
+	if err := os.WriteFile(filepath.Join(site, "index.html"), []byte(index), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(site, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(site, "assets", "app.css"), []byte("h1{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPrefix := "autoship-test-" + primitive.NewObjectID().Hex()
+	url, err := p.UploadStaticSite(ctx, site, keyPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := false
+	t.Cleanup(func() {
+		if !deleted {
+			_ = p.DeleteStaticSite(context.Background(), keyPrefix)
+		}
+	})
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != index {
+		t.Fatalf("GET %s = %d %q, want 200 %q", url, resp.StatusCode, body, index)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("GET %s Content-Type = %q, want text/html", url, ct)
+	}
+
+	if err := p.DeleteStaticSite(ctx, keyPrefix); err != nil {
+		t.Fatal(err)
+	}
+	deleted = true
+
+	a := p.(*awsProvider)
+	out, err := a.s3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: awsv2.String(a.bucket),
+		Prefix: awsv2.String(keyPrefix + "/"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Contents) != 0 {
+		t.Errorf("%d objects left under %s after DeleteStaticSite", len(out.Contents), keyPrefix)
+	}
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Errorf("GET %s after DeleteStaticSite = 200, want an error", url)
+	}
+}
@@ -2,17 +2,25 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/tracing"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // azureProvider is the Azure counterpart of awsProvider: static sites go to Blob
@@ -75,12 +83,26 @@ func newAzureProvider() (Provider, error) {
 
 func (a *azureProvider) Name() string { return "azure" }
 
+// CheckCredentials reads the properties of the configured Blob container.
+func (a *azureProvider) CheckCredentials(ctx context.Context) error {
+	_, err := a.blobClient.ServiceClient().NewContainerClient(a.container).GetProperties(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("blob container %s properties failed: %w", a.container, err)
+	}
+	return nil
+}
+
 // UploadStaticSite uploads a folder recursively to the Blob container and returns
 // the URL to its index.html.
-func (a *azureProvider) UploadStaticSite(localPath, keyPrefix string) (string, error) {
-	ctx := context.Background()
+func (a *azureProvider) UploadStaticSite(ctx context.Context, localPath, keyPrefix string) (url string, err error) {
+	ctx, span := tracing.Start(ctx, "azblob.upload_static_site",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("azblob.container", a.container),
+		attribute.String("azblob.key_prefix", keyPrefix),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	err := filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -105,8 +127,10 @@ func (a *azureProvider) UploadStaticSite(localPath, keyPrefix string) (string, e
 			HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
 		})
 		if err != nil {
+			metrics.CloudUploadErrors.WithLabelValues(a.Name()).Inc()
 			return fmt.Errorf("failed to upload blob %s: %w", blobName, err)
 		}
+		metrics.CloudUploadBytes.WithLabelValues(a.Name()).Add(float64(info.Size()))
 		return nil
 	})
 	if err != nil {
@@ -116,28 +140,81 @@ func (a *azureProvider) UploadStaticSite(localPath, keyPrefix string) (string, e
 	return fmt.Sprintf("%s/%s/index.html", a.publicBase, keyPrefix), nil
 }
 
-// AuthorizePort opens an inbound TCP rule for port on the configured NSG. The
-// rule name is derived from the port (idempotent across re-deploys) and an
-// unused priority is chosen by scanning existing rules.
-func (a *azureProvider) AuthorizePort(port int) error {
-	if a.subscriptionID == "" || a.resourceGroup == "" || a.nsgName == "" {
-		return fmt.Errorf("AZURE_SUBSCRIPTION_ID, AZURE_RESOURCE_GROUP and AZURE_NSG_NAME must be set; cannot open port %d", port)
-	}
+// blobDeleteBatch is the most sub-requests a single blob batch accepts.
+const blobDeleteBatch = 256
 
-	ctx := context.Background()
+// DeleteStaticSite lists the blobs under keyPrefix and removes them with blob
+// batch requests of up to 256 deletes each.
+func (a *azureProvider) DeleteStaticSite(ctx context.Context, keyPrefix string) (err error) {
+	ctx, span := tracing.Start(ctx, "azblob.delete_static_site",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("azblob.container", a.container),
+		attribute.String("azblob.key_prefix", keyPrefix),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	// Uses the standard Azure auth chain (env service principal or managed
-	// identity): AZURE_CLIENT_ID, AZURE_TENANT_ID, AZURE_CLIENT_SECRET.
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	prefix, err := sitePrefix(keyPrefix)
 	if err != nil {
-		return fmt.Errorf("failed to obtain Azure credentials: %w", err)
+		return err
 	}
-	client, err := armnetwork.NewSecurityRulesClient(a.subscriptionID, cred, nil)
+	containerClient := a.blobClient.ServiceClient().NewContainerClient(a.container)
+
+	// Collect the names first: deleting while paging would shift the listing.
+	var names []string
+	pager := containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: to.Ptr(prefix)})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list blobs under %s: %w", prefix, err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item != nil && item.Name != nil {
+				names = append(names, *item.Name)
+			}
+		}
+	}
+
+	for start := 0; start < len(names); start += blobDeleteBatch {
+		end := min(start+blobDeleteBatch, len(names))
+		batch, err := containerClient.NewBatchBuilder()
+		if err != nil {
+			return fmt.Errorf("failed to create blob batch: %w", err)
+		}
+		for _, name := range names[start:end] {
+			if err := batch.Delete(name, nil); err != nil {
+				return fmt.Errorf("failed to add %s to blob batch: %w", name, err)
+			}
+		}
+		resp, err := containerClient.SubmitBatch(ctx, batch, nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete blobs under %s: %w", prefix, err)
+		}
+		for _, item := range resp.Responses {
+			if item.Error != nil && !bloberror.HasCode(item.Error, bloberror.BlobNotFound) {
+				return fmt.Errorf("failed to delete blobs under %s: %w", prefix, item.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// AuthorizePort opens an inbound TCP rule for port on the configured NSG. The
+// rule name is derived from the port (idempotent across re-deploys) and an
+// unused priority is chosen by scanning existing rules.
+func (a *azureProvider) AuthorizePort(ctx context.Context, port int) (err error) {
+	ctx, span := tracing.Start(ctx, "nsg.create_security_rule",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("azure.nsg", a.nsgName),
+		attribute.Int("net.host.port", port),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := a.rulesClient()
 	if err != nil {
-		return fmt.Errorf("failed to create NSG rules client: %w", err)
+		return fmt.Errorf("%w; cannot open port %d", err, port)
 	}
 
-	ruleName := fmt.Sprintf("autoship-port-%d", port)
+	ruleName := nsgRuleName(port)
 	priority, err := a.nextFreePriority(ctx, client, ruleName)
 	if err != nil {
 		return err
@@ -153,7 +230,7 @@ func (a *azureProvider) AuthorizePort(port int) error {
 			SourcePortRange:          to.Ptr("*"),
 			DestinationAddressPrefix: to.Ptr("*"),
 			DestinationPortRange:     to.Ptr(strconv.Itoa(port)),
-			Description:              to.Ptr("Auto-opened for container hosting"),
+			Description:              to.Ptr(autoOpenedDescription),
 		},
 	}, nil)
 	if err != nil {
@@ -165,6 +242,81 @@ func (a *azureProvider) AuthorizePort(port int) error {
 	return nil
 }
 
+// RevokePort deletes the NSG rule AuthorizePort created for port. Deleting a
+// rule that does not exist succeeds.
+func (a *azureProvider) RevokePort(ctx context.Context, port int) (err error) {
+	ctx, span := tracing.Start(ctx, "nsg.delete_security_rule",
+		attribute.String("cloud.provider", a.Name()),
+		attribute.String("azure.nsg", a.nsgName),
+		attribute.Int("net.host.port", port),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	client, err := a.rulesClient()
+	if err != nil {
+		return fmt.Errorf("%w; cannot close port %d", err, port)
+	}
+	poller, err := client.BeginDelete(ctx, a.resourceGroup, a.nsgName, nsgRuleName(port), nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	var respErr *azcore.ResponseError
+	if err != nil && !(errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("failed to delete NSG rule for port %d: %w", port, err)
+	}
+	return nil
+}
+
+// AuthorizedPorts returns the ports of the NSG rules named by AuthorizePort.
+func (a *azureProvider) AuthorizedPorts(ctx context.Context) ([]int, error) {
+	client, err := a.rulesClient()
+	if err != nil {
+		return nil, err
+	}
+	var ports []int
+	pager := client.NewListPager(a.resourceGroup, a.nsgName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list NSG rules: %w", err)
+		}
+		for _, rule := range page.Value {
+			if rule == nil || rule.Name == nil || !strings.HasPrefix(*rule.Name, nsgRulePrefix) {
+				continue
+			}
+			if port, err := strconv.Atoi(strings.TrimPrefix(*rule.Name, nsgRulePrefix)); err == nil {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports, nil
+}
+
+// nsgRulePrefix starts the name of every rule AuthorizePort creates.
+const nsgRulePrefix = "autoship-port-"
+
+func nsgRuleName(port int) string { return nsgRulePrefix + strconv.Itoa(port) }
+
+// rulesClient returns an NSG rules client, or an error if the NSG settings
+// are missing.
+func (a *azureProvider) rulesClient() (*armnetwork.SecurityRulesClient, error) {
+	if a.subscriptionID == "" || a.resourceGroup == "" || a.nsgName == "" {
+		return nil, fmt.Errorf("AZURE_SUBSCRIPTION_ID, AZURE_RESOURCE_GROUP and AZURE_NSG_NAME must be set")
+	}
+
+	// Uses the standard Azure auth chain (env service principal or managed
+	// identity): AZURE_CLIENT_ID, AZURE_TENANT_ID, AZURE_CLIENT_SECRET.
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain Azure credentials: %w", err)
+	}
+	client, err := armnetwork.NewSecurityRulesClient(a.subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create NSG rules client: %w", err)
+	}
+	return client, nil
+}
+
 // nextFreePriority lists existing inbound rules and returns a priority that is
 // free. If a rule with ruleName already exists, its current priority is reused
 // so a re-deploy updates the rule in place instead of colliding.
@@ -1,12 +1,14 @@
 // Package cloud abstracts the cloud-specific pieces of Auto-Ship (static-site
 // storage and per-container firewall rules) behind a single Provider interface,
-// so the rest of the server is agnostic to whether we run on AWS or Azure.
+// so the rest of the server is agnostic to whether we run on AWS, Azure or a
+// plain host (the "local" provider).
 //
 // The active provider is chosen at startup from the CLOUD_PROVIDER env var
 // (see Init) and accessed everywhere else via Get().
 package cloud
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -15,21 +17,38 @@ import (
 // StorageProvider hosts a static site by uploading a built folder and returning
 // the public URL to its index.html. Backed by S3 (AWS) or Blob Storage (Azure).
 type StorageProvider interface {
-	UploadStaticSite(localPath, keyPrefix string) (string, error)
+	UploadStaticSite(ctx context.Context, localPath, keyPrefix string) (string, error)
+	// DeleteStaticSite removes every object uploaded under keyPrefix. A site
+	// that is already gone is not an error.
+	DeleteStaticSite(ctx context.Context, keyPrefix string) error
 }
 
 // FirewallProvider opens an inbound TCP port so a dynamic container is reachable
 // from the internet. Backed by an EC2 security group (AWS) or an NSG (Azure).
 type FirewallProvider interface {
-	AuthorizePort(port int) error
+	AuthorizePort(ctx context.Context, port int) error
+	// RevokePort closes a port opened by AuthorizePort. A port that is not
+	// open is not an error.
+	RevokePort(ctx context.Context, port int) error
+	// AuthorizedPorts lists the ports AuthorizePort has opened, so stale
+	// rules can be reconciled against the ports actually in use.
+	AuthorizedPorts(ctx context.Context) ([]int, error)
 }
 
+// autoOpenedDescription marks the firewall rules AuthorizePort creates, which
+// are the only ones RevokePort and AuthorizedPorts touch.
+const autoOpenedDescription = "Auto-opened for container hosting"
+
 // Provider bundles every cloud capability the server needs for one backend.
 type Provider interface {
 	StorageProvider
 	FirewallProvider
-	// Name returns the provider identifier, e.g. "aws" or "azure".
+	// Name returns the provider identifier, e.g. "aws", "azure" or "local".
 	Name() string
+	// CheckCredentials makes one cheap authenticated call against the
+	// storage backend so readiness probes can tell bad credentials apart
+	// from a healthy provider.
+	CheckCredentials(ctx context.Context) error
 }
 
 // active holds the provider selected by Init.
@@ -53,8 +72,10 @@ func Init(name string) error {
 		p, err = newAWSProvider()
 	case "azure":
 		p, err = newAzureProvider()
+	case "local":
+		p, err = newLocalProvider()
 	default:
-		return fmt.Errorf("unknown CLOUD_PROVIDER %q (expected \"aws\" or \"azure\")", name)
+		return fmt.Errorf("unknown CLOUD_PROVIDER %q (expected \"aws\", \"azure\" or \"local\")", name)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to initialize %q cloud provider: %w", name, err)
@@ -73,6 +94,17 @@ func Get() Provider {
 	return active
 }
 
+// sitePrefix turns a site's keyPrefix into the object-name prefix of its
+// files. The trailing slash keeps "user/app" from matching "user/app2"; an
+// empty keyPrefix is refused rather than matching the whole bucket.
+func sitePrefix(keyPrefix string) (string, error) {
+	keyPrefix = strings.Trim(keyPrefix, "/")
+	if keyPrefix == "" {
+		return "", fmt.Errorf("refusing to delete static site with empty key prefix")
+	}
+	return keyPrefix + "/", nil
+}
+
 // contentTypeByExtension returns a basic content type from a file extension.
 // Shared by the AWS and Azure storage uploaders so served assets get correct
 // Content-Type headers instead of defaulting to a download.
@@ -0,0 +1,364 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// localProvider runs Auto-Ship without a cloud account, for development, CI
+// and bare-metal hosts: static sites are copied into a directory on disk and
+// container ports are opened in the host firewall (ufw or an nftables set),
+// or not at all.
+type localProvider struct {
+	sitesDir   string
+	publicBase string // base URL for returned links, no trailing slash
+	firewall   string // "none", "ufw" or "nftables"
+	nftSet     []string
+}
+
+// Local firewall backends, chosen with LOCAL_FIREWALL.
+const (
+	localFirewallNone     = "none"
+	localFirewallUFW      = "ufw"
+	localFirewallNftables = "nftables"
+)
+
+// newLocalProvider reads LOCAL_SITES_DIR (default /var/lib/autoship/sites),
+// LOCAL_PUBLIC_BASE_URL (default http://localhost:$PORT/sites, served by the
+// server itself, see LocalSitesDir), LOCAL_FIREWALL and LOCAL_NFT_SET.
+func newLocalProvider() (Provider, error) {
+	dir := os.Getenv("LOCAL_SITES_DIR")
+	if dir == "" {
+		dir = "/var/lib/autoship/sites"
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOCAL_SITES_DIR: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create LOCAL_SITES_DIR %s: %w", dir, err)
+	}
+
+	publicBase := os.Getenv("LOCAL_PUBLIC_BASE_URL")
+	if publicBase == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+		publicBase = fmt.Sprintf("http://localhost:%s%s", port, LocalSitesPath)
+	}
+
+	p := &localProvider{
+		sitesDir:   dir,
+		publicBase: strings.TrimRight(publicBase, "/"),
+		firewall:   strings.ToLower(os.Getenv("LOCAL_FIREWALL")),
+	}
+	switch p.firewall {
+	case "", localFirewallNone:
+		p.firewall = localFirewallNone
+	case localFirewallUFW:
+		if _, err := exec.LookPath("ufw"); err != nil {
+			return nil, fmt.Errorf("LOCAL_FIREWALL=ufw but ufw is not installed: %w", err)
+		}
+	case localFirewallNftables:
+		if _, err := exec.LookPath("nft"); err != nil {
+			return nil, fmt.Errorf("LOCAL_FIREWALL=nftables but nft is not installed: %w", err)
+		}
+		set := os.Getenv("LOCAL_NFT_SET")
+		if set == "" {
+			set = "inet filter autoship_ports"
+		}
+		p.nftSet = strings.Fields(set)
+		if len(p.nftSet) != 3 {
+			return nil, fmt.Errorf("LOCAL_NFT_SET must be \"<family> <table> <set>\", got %q", set)
+		}
+	default:
+		return nil, fmt.Errorf("unknown LOCAL_FIREWALL %q (expected \"none\", \"ufw\" or \"nftables\")", p.firewall)
+	}
+	return p, nil
+}
+
+// LocalSitesPath is the URL path the server serves LocalSitesDir under.
+const LocalSitesPath = "/sites"
+
+// LocalSitesDir returns the directory static sites are copied into when the
+// local provider is active and serves its own files (LOCAL_PUBLIC_BASE_URL
+// unset), or "" otherwise.
+func LocalSitesDir() string {
+	p, ok := active.(*localProvider)
+	if !ok || os.Getenv("LOCAL_PUBLIC_BASE_URL") != "" {
+		return ""
+	}
+	return p.sitesDir
+}
+
+func (l *localProvider) Name() string { return "local" }
+
+// CheckCredentials checks that the sites directory is still a writable
+// directory.
+func (l *localProvider) CheckCredentials(ctx context.Context) error {
+	f, err := os.CreateTemp(l.sitesDir, ".check-*")
+	if err != nil {
+		return fmt.Errorf("sites directory %s is not writable: %w", l.sitesDir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// sitePath returns the directory of the site stored under keyPrefix.
+func (l *localProvider) sitePath(keyPrefix string) (string, error) {
+	prefix, err := sitePrefix(keyPrefix)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(l.sitesDir, filepath.FromSlash(prefix))
+	if !strings.HasPrefix(path, l.sitesDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("key prefix %q escapes the sites directory", keyPrefix)
+	}
+	return path, nil
+}
+
+// UploadStaticSite copies a folder into the sites directory and returns the
+// URL to its index.html. The new files are staged next to the old ones and
+// swapped in, so a redeploy never serves a half-copied site.
+func (l *localProvider) UploadStaticSite(ctx context.Context, localPath, keyPrefix string) (url string, err error) {
+	_, span := tracing.Start(ctx, "local.upload_static_site",
+		attribute.String("cloud.provider", l.Name()),
+		attribute.String("local.key_prefix", keyPrefix),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	dest, err := l.sitePath(keyPrefix)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(localPath, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(staging, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if err := copyFile(path, target); err != nil {
+			metrics.CloudUploadErrors.WithLabelValues(l.Name()).Inc()
+			return fmt.Errorf("failed to copy file %s: %w", relPath, err)
+		}
+		metrics.CloudUploadBytes.WithLabelValues(l.Name()).Add(float64(info.Size()))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chmod(staging, 0o755); err != nil {
+		return "", err
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return "", fmt.Errorf("failed to replace %s: %w", dest, err)
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		return "", fmt.Errorf("failed to move site into %s: %w", dest, err)
+	}
+	return fmt.Sprintf("%s/%s/index.html", l.publicBase, keyPrefix), nil
+}
+
+// copyFile copies the regular file src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// DeleteStaticSite removes the site's directory.
+func (l *localProvider) DeleteStaticSite(ctx context.Context, keyPrefix string) (err error) {
+	_, span := tracing.Start(ctx, "local.delete_static_site",
+		attribute.String("cloud.provider", l.Name()),
+		attribute.String("local.key_prefix", keyPrefix),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	dir, err := l.sitePath(keyPrefix)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", dir, err)
+	}
+	return nil
+}
+
+// AuthorizePort allows inbound TCP traffic to port: a commented ufw rule, or
+// an element of the nftables set that the host ruleset accepts. With
+// LOCAL_FIREWALL=none it does nothing.
+func (l *localProvider) AuthorizePort(ctx context.Context, port int) (err error) {
+	ctx, span := tracing.Start(ctx, "local.authorize_port",
+		attribute.String("cloud.provider", l.Name()),
+		attribute.String("local.firewall", l.firewall),
+		attribute.Int("net.host.port", port),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	switch l.firewall {
+	case localFirewallUFW:
+		return runFirewall(ctx, "ufw", "allow", fmt.Sprintf("%d/tcp", port), "comment", autoOpenedDescription)
+	case localFirewallNftables:
+		return runFirewall(ctx, "nft", l.nftElementArgs("add", port)...)
+	}
+	return nil
+}
+
+// RevokePort removes what AuthorizePort added for port. A port that is not
+// open is treated as success.
+func (l *localProvider) RevokePort(ctx context.Context, port int) (err error) {
+	ctx, span := tracing.Start(ctx, "local.revoke_port",
+		attribute.String("cloud.provider", l.Name()),
+		attribute.String("local.firewall", l.firewall),
+		attribute.Int("net.host.port", port),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	switch l.firewall {
+	case localFirewallUFW:
+		// ufw reports a missing rule but still exits 0.
+		return runFirewall(ctx, "ufw", "delete", "allow", fmt.Sprintf("%d/tcp", port))
+	case localFirewallNftables:
+		err = runFirewall(ctx, "nft", l.nftElementArgs("delete", port)...)
+		if err != nil && strings.Contains(err.Error(), "No such file or directory") {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// AuthorizedPorts lists the ufw rules carrying AuthorizePort's comment, or
+// the ports in the nftables set.
+func (l *localProvider) AuthorizedPorts(ctx context.Context) ([]int, error) {
+	switch l.firewall {
+	case localFirewallUFW:
+		out, err := exec.CommandContext(ctx, "ufw", "status").Output()
+		if err != nil {
+			return nil, fmt.Errorf("ufw status failed: %w", err)
+		}
+		return parseUFWPorts(string(out)), nil
+	case localFirewallNftables:
+		args := append([]string{"-j", "list", "set"}, l.nftSet...)
+		out, err := exec.CommandContext(ctx, "nft", args...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("nft list set failed: %w", err)
+		}
+		return parseNftSetPorts(out)
+	}
+	return nil, nil
+}
+
+// nftElementArgs builds `nft <op> element <family> <table> <set> { port }`.
+func (l *localProvider) nftElementArgs(op string, port int) []string {
+	args := append([]string{op, "element"}, l.nftSet...)
+	return append(args, fmt.Sprintf("{ %d }", port))
+}
+
+// runFirewall runs a firewall command and includes its output in the error.
+func runFirewall(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// parseUFWPorts picks the single-port TCP rules with AuthorizePort's comment
+// out of `ufw status` output, e.g.
+//
+//	2000/tcp                   ALLOW       Anywhere                   # Auto-opened for container hosting
+//	2000/tcp (v6)              ALLOW       Anywhere (v6)              # Auto-opened for container hosting
+func parseUFWPorts(status string) []int {
+	seen := map[int]bool{}
+	var ports []int
+	for _, line := range strings.Split(status, "\n") {
+		if !strings.HasSuffix(strings.TrimSpace(line), "# "+autoOpenedDescription) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		port, err := strconv.Atoi(strings.TrimSuffix(fields[0], "/tcp"))
+		if err != nil || seen[port] {
+			continue
+		}
+		seen[port] = true
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// parseNftSetPorts reads the single-port elements of `nft -j list set`.
+func parseNftSetPorts(out []byte) ([]int, error) {
+	var doc struct {
+		Nftables []struct {
+			Set *struct {
+				Elem []json.RawMessage `json:"elem"`
+			} `json:"set"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse nft output: %w", err)
+	}
+
+	var ports []int
+	for _, obj := range doc.Nftables {
+		if obj.Set == nil {
+			continue
+		}
+		for _, e := range obj.Set.Elem {
+			var port int
+			if json.Unmarshal(e, &port) == nil {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports, nil
+}
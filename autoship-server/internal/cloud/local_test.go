@@ -0,0 +1,97 @@
+package cloud
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSitePath(t *testing.T) {
+	l := &localProvider{sitesDir: "/srv/sites"}
+	tests := []struct {
+		name      string
+		keyPrefix string
+		want      string
+		wantErr   bool
+	}{
+		{"project prefix", "sites/64b7f0", "/srv/sites/sites/64b7f0", false},
+		{"surrounding slashes", "/octo/app/", "/srv/sites/octo/app", false},
+		{"dot-dot inside the directory", "octo/../app", "/srv/sites/app", false},
+		{"empty", "", "", true},
+		{"only slashes", "//", "", true},
+		{"dot-dot prefix", "../etc", "", true},
+		{"dot-dot only", "..", "", true},
+		{"escapes after a segment", "octo/../../etc", "", true},
+		{"resolves to the sites directory", "octo/..", "", true},
+		{"sibling with the same prefix", "../sites-other/app", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.sitePath(tt.keyPrefix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sitePath(%q) error = %v, wantErr %v", tt.keyPrefix, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("sitePath(%q) = %q, want %q", tt.keyPrefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUFWPorts(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   []int
+	}{
+		{"inactive", "Status: inactive\n", nil},
+		{"ipv4 and ipv6 duplicates", `Status: active
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW       Anywhere
+2000/tcp                   ALLOW       Anywhere                   # Auto-opened for container hosting
+2001/tcp                   ALLOW       Anywhere                   # Auto-opened for container hosting
+22/tcp (v6)                ALLOW       Anywhere (v6)
+2000/tcp (v6)              ALLOW       Anywhere (v6)              # Auto-opened for container hosting
+2001/tcp (v6)              ALLOW       Anywhere (v6)              # Auto-opened for container hosting
+`, []int{2000, 2001}},
+		{"ipv6 only", "2002/tcp (v6)              ALLOW       Anywhere (v6)              # Auto-opened for container hosting\n", []int{2002}},
+		{"other comments", "8080/tcp                   ALLOW       Anywhere                   # opened by hand\n", nil},
+		{"ranges and other protocols", `3000:3005/tcp              ALLOW       Anywhere                   # Auto-opened for container hosting
+2003/udp                   ALLOW       Anywhere                   # Auto-opened for container hosting
+`, nil},
+		{"CRLF line endings", "2004/tcp                   ALLOW       Anywhere                   # Auto-opened for container hosting\r\n", []int{2004}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUFWPorts(tt.status); !slices.Equal(got, tt.want) {
+				t.Errorf("parseUFWPorts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNftSetPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    []int
+		wantErr bool
+	}{
+		{"empty set", `{"nftables": [{"metainfo": {"json_schema_version": 1}}, {"set": {"family": "inet", "name": "autoship_ports", "table": "filter", "type": "inet_service"}}]}`, nil, false},
+		{"single ports", `{"nftables": [{"metainfo": {"json_schema_version": 1}}, {"set": {"family": "inet", "name": "autoship_ports", "table": "filter", "type": "inet_service", "elem": [2000, 2001]}}]}`, []int{2000, 2001}, false},
+		{"ranges and intervals", `{"nftables": [{"set": {"family": "inet", "name": "autoship_ports", "table": "filter", "type": "inet_service", "flags": ["interval"], "elem": [2000, {"range": [3000, 3005]}, 2002, {"prefix": {"addr": 4096, "len": 20}}]}}]}`, []int{2000, 2002}, false},
+		{"invalid JSON", `Error: No such file or directory`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNftSetPorts([]byte(tt.out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNftSetPorts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseNftSetPorts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
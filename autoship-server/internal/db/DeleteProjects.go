@@ -2,14 +2,55 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
 )
 
 func DeleteProjectByContainerName(containerName string) error {
 	collection := GetCollection("projects")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, err := collection.DeleteOne(ctx, bson.M{"containername": containerName})
+	_, err := collection.DeleteOne(ctx, bson.M{"container_name": containerName})
+	return err
+}
+
+// DeleteProjectByID removes a project document. Deleting one that is already
+// gone is not an error.
+func DeleteProjectByID(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := GetCollection("projects").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// MarkProjectDeleting records that the project's teardown has started.
+func MarkProjectDeleting(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := GetCollection("projects").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": models.ProjectStatusDeleting, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// RecordTeardownStep stores the outcome of one teardown step; stepErr nil
+// marks it done.
+func RecordTeardownStep(ctx context.Context, id primitive.ObjectID, step string, stepErr error) error {
+	result := models.TeardownStep{Done: stepErr == nil, At: time.Now()}
+	if stepErr != nil {
+		result.Error = stepErr.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := GetCollection("projects").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{fmt.Sprintf("teardown.%s", step): result}},
+	)
 	return err
 }
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveAPIToken inserts a personal API token document.
+func SaveAPIToken(ctx context.Context, token *models.APIToken) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("api_tokens").InsertOne(ctx, token)
+	if err != nil {
+		return err
+	}
+	token.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindAPIToken looks up a personal API token by the hash of its value.
+func FindAPIToken(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var token models.APIToken
+	if err := GetCollection("api_tokens").FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListAPITokens returns a user's tokens, newest first.
+func ListAPITokens(ctx context.Context, userID primitive.ObjectID) ([]models.APIToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := GetCollection("api_tokens").Find(ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []models.APIToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteAPIToken removes a token owned by userID. It reports false when no
+// such token exists.
+func DeleteAPIToken(ctx context.Context, id, userID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("api_tokens").DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
+
+// DeleteUserAPITokens removes every API token of userID, e.g. after a
+// password reset.
+func DeleteUserAPITokens(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("api_tokens").DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// TouchAPIToken records that a token was just used.
+func TouchAPIToken(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("api_tokens").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_used_at": time.Now()}},
+	)
+	return err
+}
+
+// ensureAPITokenIndexes indexes token lookups and lets Mongo drop expired
+// tokens; tokens without expires_at never expire.
+func ensureAPITokenIndexes(ctx context.Context) error {
+	_, err := GetCollection("api_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
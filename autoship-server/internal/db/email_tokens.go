@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveEmailToken inserts an email token.
+func SaveEmailToken(ctx context.Context, token *models.EmailToken) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("email_tokens").InsertOne(ctx, token)
+	if err != nil {
+		return err
+	}
+	token.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// usableEmailToken matches the unused, unexpired token with tokenHash and
+// purpose.
+func usableEmailToken(purpose, tokenHash string) bson.M {
+	return bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+}
+
+// GetEmailToken returns the unused, unexpired token with tokenHash and
+// purpose without redeeming it.
+func GetEmailToken(ctx context.Context, purpose, tokenHash string) (*models.EmailToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var token models.EmailToken
+	if err := GetCollection("email_tokens").FindOne(ctx, usableEmailToken(purpose, tokenHash)).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ConsumeEmailToken marks the unused, unexpired token with tokenHash and
+// purpose as used and returns it. The update is atomic, so a token can only
+// be redeemed once; mongo.ErrNoDocuments means it is unknown, used or expired.
+func ConsumeEmailToken(ctx context.Context, purpose, tokenHash string) (*models.EmailToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var token models.EmailToken
+	err := GetCollection("email_tokens").FindOneAndUpdate(ctx,
+		usableEmailToken(purpose, tokenHash),
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteEmailTokens removes a user's outstanding tokens for purpose, so that
+// only the most recently mailed link works.
+func DeleteEmailTokens(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("email_tokens").DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose})
+	return err
+}
+
+// ensureEmailTokenIndexes creates the lookup index and a TTL index that
+// drops expired tokens.
+func ensureEmailTokenIndexes(ctx context.Context) error {
+	_, err := GetCollection("email_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EnsureIndexes creates every index the server relies on. Index creation is
+// idempotent, so it is safe to call on each startup.
+func EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for name, ensure := range map[string]func(context.Context) error{
+		"users":      ensureUserIndexes,
+		"tokens":     ensureTokenIndexes,
+		"api_tokens": ensureAPITokenIndexes,
+		"email":      ensureEmailTokenIndexes,
+		"logins":     ensureLoginAttemptIndexes,
+		"projects":   ensureProjectIndexes,
+		"orgs":       ensureOrgIndexes,
+		"ports":      ensurePortIndexes,
+	} {
+		if err := ensure(ctx); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %w", name, err)
+		}
+	}
+	return nil
+}
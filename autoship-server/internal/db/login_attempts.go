@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// loginAttemptsRetention is how long failure counts are kept after the last
+// failed attempt.
+const loginAttemptsRetention = 24 * time.Hour
+
+// GetLoginAttempts returns the failure record for key, or nil if there is none.
+func GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var attempts models.LoginAttempts
+	err := GetCollection("login_attempts").FindOne(ctx, bson.M{"key": key}).Decode(&attempts)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// RecordLoginFailure counts a failed attempt for key and locks it until
+// now+lockFor(failures) when lockFor returns a positive duration.
+func RecordLoginFailure(ctx context.Context, key string, lockFor func(failures int) time.Duration) (*models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var attempts models.LoginAttempts
+	err := GetCollection("login_attempts").FindOneAndUpdate(ctx,
+		bson.M{"key": key},
+		bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"updated_at": now}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	if d := lockFor(attempts.Failures); d > 0 {
+		until := now.Add(d)
+		if _, err := GetCollection("login_attempts").UpdateOne(ctx,
+			bson.M{"key": key},
+			bson.M{"$set": bson.M{"locked_until": until}},
+		); err != nil {
+			return nil, err
+		}
+		attempts.LockedUntil = &until
+	}
+	return &attempts, nil
+}
+
+// ClearLoginFailures forgets the failures recorded for key.
+func ClearLoginFailures(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("login_attempts").DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
+
+// ensureLoginAttemptIndexes makes keys unique and lets Mongo forget failures
+// a day after the last one.
+func ensureLoginAttemptIndexes(ctx context.Context) error {
+	_, err := GetCollection("login_attempts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "updated_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(loginAttemptsRetention / time.Second))},
+	})
+	return err
+}
@@ -3,11 +3,12 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var client *mongo.Client
@@ -18,34 +19,45 @@ func SetMongoURI(uri string) {
 	mongoURI = uri
 }
 
-// Connect establishes a connection to MongoDB.
-func Connect() {
+// Connect establishes a connection to MongoDB and verifies it with a ping.
+func Connect() error {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(mongoURI).SetServerAPIOptions(serverAPI)
+	opts := options.Client().ApplyURI(mongoURI).SetServerAPIOptions(serverAPI).SetMonitor(otelmongo.NewMonitor())
 
-	var err error
-	client, err = mongo.Connect(context.TODO(), opts)
+	c, err := mongo.Connect(context.TODO(), opts)
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
 
 	// Ping the database to verify the connection.
-	if err := client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "ping", Value: 1}}).Err(); err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+	if err := c.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "ping", Value: 1}}).Err(); err != nil {
+		_ = c.Disconnect(context.TODO())
+		return fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
-	fmt.Println("Successfully connected to MongoDB!")
+	client = c
+	slog.Info("connected to MongoDB")
+	return nil
 }
 
 // Disconnect closes the MongoDB connection.
 func Disconnect() {
 	if client != nil {
 		if err := client.Disconnect(context.TODO()); err != nil {
-			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
+			slog.Error("failed to disconnect from MongoDB", "error", err)
+			return
 		}
-		fmt.Println("Connection to MongoDB closed.")
+		slog.Info("MongoDB connection closed")
 	}
 }
 
+// Ping checks that the MongoDB deployment is reachable.
+func Ping(ctx context.Context) error {
+	if client == nil {
+		return fmt.Errorf("mongo client not connected")
+	}
+	return client.Ping(ctx, nil)
+}
+
 // GetCollection returns a collection from the database.
 func GetCollection(name string) *mongo.Collection {
 	return client.Database("autoship").Collection(name)
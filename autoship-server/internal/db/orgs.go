@@ -0,0 +1,274 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateOrg inserts an organization and makes creator its owner.
+func CreateOrg(ctx context.Context, org *models.Organization, creator primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("organizations").InsertOne(ctx, org)
+	if err != nil {
+		return err
+	}
+	org.ID = res.InsertedID.(primitive.ObjectID)
+
+	_, err = GetCollection("memberships").InsertOne(ctx, models.Membership{
+		OrgID:     org.ID,
+		UserID:    creator,
+		Role:      models.RoleOwner,
+		CreatedAt: org.CreatedAt,
+	})
+	if err != nil {
+		// Don't leave an org nobody can manage.
+		_, _ = GetCollection("organizations").DeleteOne(ctx, bson.M{"_id": org.ID})
+		return err
+	}
+	return nil
+}
+
+// GetOrg looks up an organization by ID.
+func GetOrg(ctx context.Context, id primitive.ObjectID) (*models.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var org models.Organization
+	if err := GetCollection("organizations").FindOne(ctx, bson.M{"_id": id}).Decode(&org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// OrgWithRole is an organization as seen by one of its members.
+type OrgWithRole struct {
+	models.Organization `bson:",inline"`
+	Role                string `bson:"role" json:"role"`
+}
+
+// ListOrgsForUser returns every organization userID belongs to, with their role.
+func ListOrgsForUser(ctx context.Context, userID primitive.ObjectID) ([]OrgWithRole, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := GetCollection("memberships").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$lookup", Value: bson.M{"from": "organizations", "localField": "org_id", "foreignField": "_id", "as": "org"}}},
+		{{Key: "$unwind", Value: "$org"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{"$mergeObjects": bson.A{"$org", bson.M{"role": "$role"}}}}}},
+		{{Key: "$sort", Value: bson.M{"name": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orgs := []OrgWithRole{}
+	if err := cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// GetMembership returns userID's membership in orgID.
+func GetMembership(ctx context.Context, orgID, userID primitive.ObjectID) (*models.Membership, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var m models.Membership
+	if err := GetCollection("memberships").FindOne(ctx, bson.M{"org_id": orgID, "user_id": userID}).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// AddMember inserts a membership. The unique (org_id, user_id) index makes a
+// second insert for the same user fail with a duplicate key error.
+func AddMember(ctx context.Context, m *models.Membership) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("memberships").InsertOne(ctx, m)
+	if err != nil {
+		return err
+	}
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ListMembers returns the members of orgID with their emails.
+func ListMembers(ctx context.Context, orgID primitive.ObjectID) ([]models.Member, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := GetCollection("memberships").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"org_id": orgID}}},
+		{{Key: "$lookup", Value: bson.M{"from": "users", "localField": "user_id", "foreignField": "_id", "as": "user"}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$project", Value: bson.M{"user_id": 1, "role": 1, "created_at": 1, "email": "$user.email", "totp_enabled": "$user.totp_enabled"}}},
+		{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	members := []models.Member{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SetOrgRequire2FA turns the organization's two-factor requirement on or off.
+func SetOrgRequire2FA(ctx context.Context, orgID primitive.ObjectID, require bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("organizations").UpdateOne(ctx,
+		bson.M{"_id": orgID},
+		bson.M{"$set": bson.M{"require_2fa": require, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// UpdateMemberRole changes a member's role. It reports false when userID is
+// not a member of orgID.
+func UpdateMemberRole(ctx context.Context, orgID, userID primitive.ObjectID, role string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("memberships").UpdateOne(ctx,
+		bson.M{"org_id": orgID, "user_id": userID},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// RemoveMember deletes userID's membership in orgID. It reports false when
+// there was none.
+func RemoveMember(ctx context.Context, orgID, userID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("memberships").DeleteOne(ctx, bson.M{"org_id": orgID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
+
+// CountOwners returns how many owners orgID has.
+func CountOwners(ctx context.Context, orgID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return GetCollection("memberships").CountDocuments(ctx, bson.M{"org_id": orgID, "role": models.RoleOwner})
+}
+
+// SaveInvite inserts an organization invite.
+func SaveInvite(ctx context.Context, invite *models.Invite) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("invites").InsertOne(ctx, invite)
+	if err != nil {
+		return err
+	}
+	invite.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindInvite looks up an invite by the hash of its token.
+func FindInvite(ctx context.Context, tokenHash string) (*models.Invite, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var invite models.Invite
+	if err := GetCollection("invites").FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// ListPendingInvites returns the unaccepted invites of orgID.
+func ListPendingInvites(ctx context.Context, orgID primitive.ObjectID) ([]models.Invite, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := GetCollection("invites").Find(ctx,
+		bson.M{"org_id": orgID, "accepted_at": bson.M{"$exists": false}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invites := []models.Invite{}
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// MarkInviteAccepted claims an invite. It reports false when the invite was
+// already accepted, so a token can only be used once.
+func MarkInviteAccepted(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("invites").UpdateOne(ctx,
+		bson.M{"_id": id, "accepted_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"accepted_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// DeleteInvite removes a pending invite of orgID. It reports false when there
+// was none.
+func DeleteInvite(ctx context.Context, orgID, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("invites").DeleteOne(ctx, bson.M{"_id": id, "org_id": orgID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
+
+// ensureOrgIndexes keeps slugs and memberships unique and expires old invites.
+func ensureOrgIndexes(ctx context.Context) error {
+	if _, err := GetCollection("organizations").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	if _, err := GetCollection("memberships").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}); err != nil {
+		return err
+	}
+	_, err := GetCollection("invites").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "org_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertPortLease leases port to containerName. It reports false when the
+// port is already leased; the unique index on port makes this the atomic
+// claim.
+func InsertPortLease(ctx context.Context, port int, containerName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("ports").InsertOne(ctx, models.PortLease{
+		Port:          port,
+		ContainerName: containerName,
+		LeasedAt:      time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeletePortLease releases port if it is still leased to containerName, so a
+// late release cannot free a port that has been leased again.
+func DeletePortLease(ctx context.Context, port int, containerName string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("ports").DeleteOne(ctx, bson.M{"port": port, "container_name": containerName})
+	return err
+}
+
+// ListPortLeases returns every lease, by port.
+func ListPortLeases(ctx context.Context) ([]models.PortLease, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := GetCollection("ports").Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "port", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	leases := []models.PortLease{}
+	if err := cursor.All(ctx, &leases); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// ensurePortIndexes makes port unique, which is what keeps two deployments
+// from leasing the same port.
+//
+// Documents of the old port manager ({port, status, containerName,
+// timestamp}, possibly several per port) are deleted first: they would fail
+// the index build, and nothing releases them. The ports they held are still
+// reserved while a project records them; the rest belonged to failed
+// deployments.
+func ensurePortIndexes(ctx context.Context) error {
+	res, err := GetCollection("ports").DeleteMany(ctx, bson.M{"container_name": bson.M{"$exists": false}})
+	if err != nil {
+		return fmt.Errorf("failed to delete legacy port documents: %w", err)
+	}
+	if res.DeletedCount > 0 {
+		slog.Info("deleted legacy port documents", "count", res.DeletedCount)
+	}
+
+	_, err = GetCollection("ports").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "port", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
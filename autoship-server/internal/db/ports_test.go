@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEnsurePortIndexesDropsLegacyDocuments(t *testing.T) {
+	connectTest(t)
+	ctx := context.Background()
+	coll := GetCollection("ports")
+
+	// The old port manager's upsert could leave several documents per port,
+	// which only insert without the unique index.
+	_, _ = coll.Indexes().DropOne(ctx, "port_1")
+	const port = 64999
+	for _, status := range []string{"used", "used", "available"} {
+		if _, err := coll.InsertOne(ctx, bson.M{
+			"port": port, "status": status, "containerName": "autoship-Legacy-app-1", "timestamp": time.Now(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leased, err := InsertPortLease(ctx, port-1, "autoship-current")
+	if err != nil || !leased {
+		t.Fatalf("InsertPortLease = %v, %v", leased, err)
+	}
+	t.Cleanup(func() { _ = DeletePortLease(context.Background(), port-1, "autoship-current") })
+
+	if err := ensurePortIndexes(ctx); err != nil {
+		t.Fatalf("ensurePortIndexes with legacy documents: %v", err)
+	}
+
+	if n, err := coll.CountDocuments(ctx, bson.M{"port": port}); err != nil || n != 0 {
+		t.Errorf("%d legacy documents left for port %d (%v), want 0", n, port, err)
+	}
+	if n, err := coll.CountDocuments(ctx, bson.M{"port": port - 1}); err != nil || n != 1 {
+		t.Errorf("%d leases for port %d (%v), want 1", n, port-1, err)
+	}
+	if leased, err := InsertPortLease(ctx, port-1, "autoship-other"); err != nil || leased {
+		t.Errorf("second lease of port %d = %v, %v; want false (unique index)", port-1, leased, err)
+	}
+}
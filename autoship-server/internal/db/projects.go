@@ -2,15 +2,20 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // SaveProject inserts a project document into the "projects" collection.
-func SaveProject(project *models.Project) error {
+func SaveProject(ctx context.Context, project *models.Project) error {
 	collection := GetCollection("projects") // use GetCollection from mongo.go
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	_, err := collection.InsertOne(ctx, project)
@@ -20,3 +25,243 @@ func SaveProject(project *models.Project) error {
 // func GetCollection(name string) *mongo.Collection {
 // 	return Client.Database("autoship").Collection(name)
 // }
+
+// GetProjectByID looks up a project by its hex ObjectID.
+func GetProjectByID(ctx context.Context, id string) (*models.Project, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project id: %w", err)
+	}
+
+	collection := GetCollection("projects")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var project models.Project
+	if err := collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetProjectByContainerName looks up a dynamic project by its container name.
+func GetProjectByContainerName(ctx context.Context, containerName string) (*models.Project, error) {
+	collection := GetCollection("projects")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var project models.Project
+	if err := collection.FindOne(ctx, bson.M{"container_name": containerName}).Decode(&project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListProjectsByOwner returns the personal (non-organization) projects of
+// ownerID, newest first.
+func ListProjectsByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Project, error) {
+	collection := GetCollection("projects")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"owner_id": ownerID, "org_id": bson.M{"$exists": false}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []models.Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// ownerlessFilter matches personal projects saved before projects recorded
+// their owner.
+func ownerlessFilter() bson.M {
+	return bson.M{
+		"owner_id": bson.M{"$in": bson.A{nil, primitive.NilObjectID}},
+		"org_id":   bson.M{"$exists": false},
+	}
+}
+
+// ListOwnerlessProjects returns the projects without an owner, limited to the
+// repositories of repoOwner unless it is empty.
+func ListOwnerlessProjects(ctx context.Context, repoOwner string) ([]models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := ownerlessFilter()
+	if repoOwner != "" {
+		filter["username"] = repoOwner
+	}
+	cursor, err := GetCollection("projects").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []models.Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// SetProjectOwner records ownerID as the owner of project id if it has none
+// yet. It reports false when the project already has an owner.
+func SetProjectOwner(ctx context.Context, id, ownerID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := ownerlessFilter()
+	filter["_id"] = id
+	res, err := GetCollection("projects").UpdateOne(ctx, filter,
+		bson.M{"$set": bson.M{"owner_id": ownerID, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ListProjectsByOrg returns every project of orgID, newest first.
+func ListProjectsByOrg(ctx context.Context, orgID primitive.ObjectID) ([]models.Project, error) {
+	collection := GetCollection("projects")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"org_id": orgID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []models.Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// ListProjectHostPorts returns the host ports of every dynamic project.
+func ListProjectHostPorts(ctx context.Context) ([]int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	values, err := GetCollection("projects").Distinct(ctx, "host_port", bson.M{"host_port": bson.M{"$gt": 0}})
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]int, 0, len(values))
+	for _, v := range values {
+		switch n := v.(type) {
+		case int32:
+			ports = append(ports, int(n))
+		case int64:
+			ports = append(ports, int(n))
+		}
+	}
+	return ports, nil
+}
+
+// LegacySiteShared reports whether a static project other than exclude still
+// has its files under <username>/<repoName>, the prefix every deployment of a
+// repository was uploaded under before projects got their own.
+func LegacySiteShared(ctx context.Context, username, repoName string, exclude primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n, err := GetCollection("projects").CountDocuments(ctx, bson.M{
+		"_id":            bson.M{"$ne": exclude},
+		"project_type":   "static",
+		"username":       username,
+		"repo_name":      repoName,
+		"storage_prefix": bson.M{"$in": bson.A{nil, ""}},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ProjectSettings are the user-editable settings of a project. Nil fields are
+// left unchanged.
+type ProjectSettings struct {
+	StartCommand *string
+	EnvContent   *string
+	Branch       *string
+	MemoryMB     *int
+}
+
+// UpdateProjectSettings applies s to the project.
+func UpdateProjectSettings(ctx context.Context, id primitive.ObjectID, s ProjectSettings) error {
+	set := bson.M{"updated_at": time.Now()}
+	if s.StartCommand != nil {
+		set["start_command"] = *s.StartCommand
+	}
+	if s.EnvContent != nil {
+		set["env_content"] = *s.EnvContent
+	}
+	if s.Branch != nil {
+		set["branch"] = *s.Branch
+	}
+	if s.MemoryMB != nil {
+		set["memory_mb"] = *s.MemoryMB
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := GetCollection("projects").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// SetProjectStatus records that the project's container was stopped or
+// started.
+func SetProjectStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("projects").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// UpdateProjectDeployment stores where a redeploy of p now runs: its URL and,
+// for dynamic projects, the new container, or for static ones the new size.
+func UpdateProjectDeployment(ctx context.Context, p *models.Project) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("projects").UpdateOne(ctx,
+		bson.M{"_id": p.ID},
+		bson.M{"$set": bson.M{
+			"hosted_url":     p.HostedURL,
+			"subdomain":      p.Subdomain,
+			"container_name": p.ContainerName,
+			"container_port": p.ContainerPort,
+			"host_port":      p.HostPort,
+			"build_log_path": p.BuildLogPath,
+			"storage_bytes":  p.StorageBytes,
+			"storage_prefix": p.StoragePrefix,
+			"status":         p.Status,
+			"updated_at":     time.Now(),
+		}},
+	)
+	return err
+}
+
+// ensureProjectIndexes indexes the per-owner and per-organization listings.
+func ensureProjectIndexes(ctx context.Context) error {
+	_, err := GetCollection("projects").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "org_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "container_name", Value: 1}}},
+	})
+	return err
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// connectTest connects to the MongoDB named by AUTOSHIP_TEST_MONGO_URI, or
+// skips the test. Its "autoship" database is written to, so point it at a
+// throwaway instance.
+func connectTest(t *testing.T) {
+	t.Helper()
+	uri := os.Getenv("AUTOSHIP_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("AUTOSHIP_TEST_MONGO_URI not set")
+	}
+	if client != nil {
+		return
+	}
+	SetMongoURI(uri)
+	if err := Connect(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetProjectOwnerOnlyAssignsOwnerless(t *testing.T) {
+	connectTest(t)
+	ctx := context.Background()
+	repoOwner := "backfill-" + primitive.NewObjectID().Hex()
+
+	// A project saved before owners were recorded has no owner_id at all.
+	legacyID := primitive.NewObjectID()
+	if _, err := GetCollection("projects").InsertOne(ctx, bson.M{
+		"_id": legacyID, "username": repoOwner, "repo_name": "legacy", "created_at": time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	owned := &models.Project{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), Username: repoOwner, RepoName: "owned"}
+	if err := SaveProject(ctx, owned); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = GetCollection("projects").DeleteMany(ctx, bson.M{"username": repoOwner})
+	})
+
+	projects, err := ListOwnerlessProjects(ctx, repoOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || projects[0].ID != legacyID {
+		t.Fatalf("ListOwnerlessProjects = %v, want only %s", projects, legacyID.Hex())
+	}
+
+	newOwner := primitive.NewObjectID()
+	if ok, err := SetProjectOwner(ctx, legacyID, newOwner); err != nil || !ok {
+		t.Fatalf("SetProjectOwner(legacy) = %v, %v, want true", ok, err)
+	}
+	if ok, err := SetProjectOwner(ctx, owned.ID, newOwner); err != nil || ok {
+		t.Fatalf("SetProjectOwner(owned) = %v, %v, want false", ok, err)
+	}
+
+	got, err := GetProjectByID(ctx, legacyID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OwnerID != newOwner {
+		t.Errorf("legacy project owner = %s, want %s", got.OwnerID.Hex(), newOwner.Hex())
+	}
+	if got, _ := GetProjectByID(ctx, owned.ID.Hex()); got.OwnerID != owned.OwnerID {
+		t.Errorf("owned project owner changed to %s", got.OwnerID.Hex())
+	}
+	if projects, _ := ListOwnerlessProjects(ctx, repoOwner); len(projects) != 0 {
+		t.Errorf("ListOwnerlessProjects after backfill = %d projects, want 0", len(projects))
+	}
+}
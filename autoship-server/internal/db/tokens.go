@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveRefreshToken inserts a refresh token document.
+func SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("refresh_tokens").InsertOne(ctx, token)
+	if err != nil {
+		return err
+	}
+	token.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindRefreshToken looks up a refresh token by the hash of its value.
+func FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var token models.RefreshToken
+	if err := GetCollection("refresh_tokens").FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken marks a single refresh token as used. It reports false
+// when the token had already been revoked, so two concurrent refreshes with
+// the same token cannot both succeed.
+func RevokeRefreshToken(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("refresh_tokens").UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token descending from the same login.
+func RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("refresh_tokens").UpdateMany(ctx,
+		bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of userID, signing the
+// user out everywhere once their access tokens expire.
+func RevokeUserRefreshTokens(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("refresh_tokens").UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeAccessToken denylists an access token's jti until expiresAt.
+func RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("revoked_tokens").UpdateOne(ctx,
+		bson.M{"jti": jti},
+		bson.M{"$setOnInsert": models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// IsAccessTokenRevoked reports whether jti is on the denylist.
+func IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := GetCollection("revoked_tokens").FindOne(ctx, bson.M{"jti": jti}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ensureTokenIndexes creates lookup indexes and TTL indexes that let Mongo
+// drop expired refresh tokens and denylist entries on its own.
+func ensureTokenIndexes(ctx context.Context) error {
+	if _, err := GetCollection("refresh_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}); err != nil {
+		return err
+	}
+	_, err := GetCollection("revoked_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "jti", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
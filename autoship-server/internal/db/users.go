@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetUserByID looks up a user by ObjectID.
+func GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := GetCollection("users").FindOne(ctx, bson.M{"_id": id}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByEmail looks up a user by email address.
+func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := GetCollection("users").FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByIdentity looks up the user an external login account is linked to.
+func GetUserByIdentity(ctx context.Context, provider, accountID string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := GetCollection("users").FindOne(ctx, bson.M{"identities." + provider: accountID}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateUser inserts a user and sets its ID.
+func CreateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("users").InsertOne(ctx, user)
+	if err != nil {
+		return err
+	}
+	user.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// LinkIdentity attaches an external login account to userID. The unique
+// identities.<provider> index rejects an account already linked to someone
+// else.
+func LinkIdentity(ctx context.Context, userID primitive.ObjectID, provider, accountID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"identities." + provider: accountID, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// MarkEmailVerified records that userID has proven control of email. It is a
+// no-op when the user's address has changed since the token was sent.
+func MarkEmailVerified(ctx context.Context, userID primitive.ObjectID, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "email": email},
+		bson.M{"$set": bson.M{"email_verified": true, "email_verified_at": now, "updated_at": now}},
+	)
+	return err
+}
+
+// UpdatePassword replaces the bcrypt hash of userID's password.
+func UpdatePassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"password": passwordHash, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// SetPendingTOTP stores a new, not yet confirmed TOTP secret for userID.
+// It fails to match users who already have TOTP enabled.
+func SetPendingTOTP(ctx context.Context, userID primitive.ObjectID, secret string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "totp_enabled": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"totp_secret": secret, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// EnableTOTP turns on two-factor authentication for userID with the secret
+// that was confirmed (so a concurrent re-enrolment cannot swap it), records
+// the step of the confirming code and stores the recovery code hashes.
+func EnableTOTP(ctx context.Context, userID primitive.ObjectID, secret string, step int64, recoveryHashes []string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "totp_secret": secret, "totp_enabled": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+			"recovery_codes": recoveryHashes,
+			"updated_at":     time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// DisableTOTP turns off two-factor authentication and drops the secret and
+// recovery codes.
+func DisableTOTP(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set":   bson.M{"totp_enabled": false, "updated_at": time.Now()},
+			"$unset": bson.M{"totp_secret": "", "totp_last_step": "", "recovery_codes": ""},
+		},
+	)
+	return err
+}
+
+// UseTOTPStep records step as the last accepted TOTP step. It reports false
+// when a code for this or a later step was already accepted.
+func UseTOTPStep(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "totp_enabled": true, "$or": bson.A{
+			bson.M{"totp_last_step": bson.M{"$exists": false}},
+			bson.M{"totp_last_step": bson.M{"$lt": step}},
+		}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ConsumeRecoveryCode removes the recovery code with codeHash from userID.
+// It reports false when the user has no such unused code.
+func ConsumeRecoveryCode(ctx context.Context, userID primitive.ObjectID, codeHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "totp_enabled": true, "recovery_codes": codeHash},
+		bson.M{"$pull": bson.M{"recovery_codes": codeHash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ReplaceRecoveryCodes swaps userID's recovery codes for a new set.
+func ReplaceRecoveryCodes(ctx context.Context, userID primitive.ObjectID, recoveryHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "totp_enabled": true},
+		bson.M{"$set": bson.M{"recovery_codes": recoveryHashes, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// IdentityProviders are the login providers whose account IDs are indexed.
+var IdentityProviders = []string{"github", "gitlab", "bitbucket", "sso"}
+
+// ensureUserIndexes makes each external account linkable to at most one user.
+func ensureUserIndexes(ctx context.Context) error {
+	indexes := make([]mongo.IndexModel, 0, len(IdentityProviders))
+	for _, provider := range IdentityProviders {
+		key := "identities." + provider
+		indexes = append(indexes, mongo.IndexModel{
+			Keys: bson.D{{Key: key, Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{key: bson.M{"$exists": true}}),
+		})
+	}
+	_, err := GetCollection("users").Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,130 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// bitbucketProvider talks to Bitbucket Cloud.
+type bitbucketProvider struct {
+	clientID, clientSecret, redirectURI string
+}
+
+func newBitbucketProvider() *bitbucketProvider {
+	return &bitbucketProvider{
+		clientID:     os.Getenv("BITBUCKET_CLIENT_ID"),
+		clientSecret: os.Getenv("BITBUCKET_CLIENT_SECRET"),
+		redirectURI:  os.Getenv("BITBUCKET_REDIRECT_URI"),
+	}
+}
+
+func (b *bitbucketProvider) Name() string { return "bitbucket" }
+
+func (b *bitbucketProvider) MatchesHost(host string) bool {
+	return host == "bitbucket.org" || host == "www.bitbucket.org"
+}
+
+// ParseRepoPath accepts https URLs with or without the "user@" prefix
+// Bitbucket puts in its clone URLs; the first two segments are the workspace
+// and the repository slug.
+func (b *bitbucketProvider) ParseRepoPath(_, path string) (*Repo, error) {
+	parts, err := splitRepoPath(path)
+	if err != nil {
+		return nil, err
+	}
+	workspace, slug := parts[0], parts[1]
+	return &Repo{
+		Provider: b.Name(),
+		Host:     "bitbucket.org",
+		Owner:    workspace,
+		Name:     slug,
+		CloneURL: fmt.Sprintf("https://bitbucket.org/%s/%s.git", workspace, slug),
+	}, nil
+}
+
+func (b *bitbucketProvider) OAuthConfigured() bool {
+	return b.clientID != "" && b.clientSecret != ""
+}
+
+// AuthURL omits scopes: Bitbucket takes them from the OAuth consumer, which
+// needs the "account" and "email" permissions.
+func (b *bitbucketProvider) AuthURL(state, codeChallenge string) string {
+	q := url.Values{
+		"client_id":             {b.clientID},
+		"response_type":         {"code"},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	if b.redirectURI != "" {
+		q.Set("redirect_uri", b.redirectURI)
+	}
+	return "https://bitbucket.org/site/oauth2/authorize?" + q.Encode()
+}
+
+func (b *bitbucketProvider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	if !b.OAuthConfigured() {
+		return "", ErrNotConfigured
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"code_verifier": {codeVerifier},
+	}
+	if b.redirectURI != "" {
+		form.Set("redirect_uri", b.redirectURI)
+	}
+	return postForm(ctx, "https://bitbucket.org/site/oauth2/access_token", form, b.clientID, b.clientSecret)
+}
+
+// Identity keys accounts by their UUID and takes the primary email when it is
+// confirmed.
+func (b *bitbucketProvider) Identity(ctx context.Context, accessToken string) (*Identity, error) {
+	var user struct {
+		UUID     string `json:"uuid"`
+		Username string `json:"username"`
+		Nickname string `json:"nickname"`
+	}
+	if err := getJSON(ctx, accessToken, "https://api.bitbucket.org/2.0/user", &user); err != nil {
+		return nil, err
+	}
+	if user.UUID == "" {
+		return nil, fmt.Errorf("bitbucket user response has no uuid")
+	}
+	login := user.Username
+	if login == "" {
+		login = user.Nickname
+	}
+
+	var emails struct {
+		Values []struct {
+			Email       string `json:"email"`
+			IsPrimary   bool   `json:"is_primary"`
+			IsConfirmed bool   `json:"is_confirmed"`
+		} `json:"values"`
+	}
+	if err := getJSON(ctx, accessToken, "https://api.bitbucket.org/2.0/user/emails", &emails); err != nil {
+		return nil, err
+	}
+	email := ""
+	for _, e := range emails.Values {
+		if e.IsConfirmed && (e.IsPrimary || email == "") {
+			email = strings.ToLower(e.Email)
+		}
+	}
+
+	return &Identity{ID: user.UUID, Login: login, Email: email}, nil
+}
+
+// CloneCredentials treats accessToken as an OAuth or repository access token.
+// App passwords need the account username instead and are sent as
+// "username:app_password".
+func (b *bitbucketProvider) CloneCredentials(accessToken string) (string, string) {
+	if user, pass, ok := strings.Cut(accessToken, ":"); ok {
+		return user, pass
+	}
+	return "x-token-auth", accessToken
+}
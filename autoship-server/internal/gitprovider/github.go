@@ -0,0 +1,111 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// githubProvider talks to github.com.
+type githubProvider struct {
+	clientID, clientSecret, redirectURI string
+}
+
+func newGitHubProvider() *githubProvider {
+	return &githubProvider{
+		clientID:     os.Getenv("GITHUB_CLIENT_ID"),
+		clientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+		redirectURI:  strings.TrimSpace(os.Getenv("GITHUB_REDIRECT_URI")),
+	}
+}
+
+func (g *githubProvider) Name() string { return "github" }
+
+func (g *githubProvider) MatchesHost(host string) bool {
+	return host == "github.com" || host == "www.github.com"
+}
+
+func (g *githubProvider) ParseRepoPath(_, path string) (*Repo, error) {
+	parts, err := splitRepoPath(path)
+	if err != nil {
+		return nil, err
+	}
+	owner, name := parts[0], parts[1]
+	return &Repo{
+		Provider: g.Name(),
+		Host:     "github.com",
+		Owner:    owner,
+		Name:     name,
+		CloneURL: fmt.Sprintf("https://github.com/%s/%s.git", owner, name),
+	}, nil
+}
+
+func (g *githubProvider) OAuthConfigured() bool {
+	return g.clientID != "" && g.clientSecret != ""
+}
+
+func (g *githubProvider) AuthURL(state, codeChallenge string) string {
+	q := url.Values{
+		"client_id":             {g.clientID},
+		"redirect_uri":          {g.redirectURI},
+		"scope":                 {"read:user user:email"},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+		"allow_signup":          {"true"},
+	}
+	return "https://github.com/login/oauth/authorize?" + q.Encode()
+}
+
+func (g *githubProvider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	if !g.OAuthConfigured() {
+		return "", ErrNotConfigured
+	}
+	return postForm(ctx, "https://github.com/login/oauth/access_token", url.Values{
+		"client_id":     {g.clientID},
+		"client_secret": {g.clientSecret},
+		"code":          {code},
+		"redirect_uri":  {g.redirectURI},
+		"code_verifier": {codeVerifier},
+	}, "", "")
+}
+
+// Identity uses /user for the account ID and /user/emails for a verified
+// email: the public profile email is not guaranteed to be verified.
+func (g *githubProvider) Identity(ctx context.Context, accessToken string) (*Identity, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := getJSON(ctx, accessToken, "https://api.github.com/user", &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("GitHub user response has no id")
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, accessToken, "https://api.github.com/user/emails", &emails); err != nil {
+		return nil, err
+	}
+	email := ""
+	for _, e := range emails {
+		if e.Verified && (e.Primary || email == "") {
+			email = strings.ToLower(e.Email)
+		}
+	}
+
+	return &Identity{ID: strconv.FormatInt(user.ID, 10), Login: user.Login, Email: email}, nil
+}
+
+// CloneCredentials works for OAuth tokens, fine-grained and classic PATs.
+func (g *githubProvider) CloneCredentials(accessToken string) (string, string) {
+	return "x-access-token", accessToken
+}
@@ -0,0 +1,129 @@
+package gitprovider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultGitLabURL is used when GITLAB_URL is unset.
+const defaultGitLabURL = "https://gitlab.com"
+
+// gitlabProvider talks to gitlab.com or a self-hosted instance set by
+// GITLAB_URL. Repository URLs on gitlab.com are accepted either way.
+type gitlabProvider struct {
+	baseURL                             string // e.g. https://gitlab.example.com, no trailing slash
+	host                                string
+	clientID, clientSecret, redirectURI string
+}
+
+func newGitLabProvider() (*gitlabProvider, error) {
+	base := strings.TrimRight(os.Getenv("GITLAB_URL"), "/")
+	if base == "" {
+		base = defaultGitLabURL
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid GITLAB_URL %q", base)
+	}
+	return &gitlabProvider{
+		baseURL:      base,
+		host:         strings.ToLower(u.Host),
+		clientID:     os.Getenv("GITLAB_CLIENT_ID"),
+		clientSecret: os.Getenv("GITLAB_CLIENT_SECRET"),
+		redirectURI:  os.Getenv("GITLAB_REDIRECT_URI"),
+	}, nil
+}
+
+func (g *gitlabProvider) Name() string { return "gitlab" }
+
+func (g *gitlabProvider) MatchesHost(host string) bool {
+	return host == g.host || host == "gitlab.com"
+}
+
+// ParseRepoPath allows nested groups: the last segment is the project, the
+// rest is its namespace. GitLab UI URLs ("/-/tree/main") are trimmed.
+func (g *gitlabProvider) ParseRepoPath(host, path string) (*Repo, error) {
+	if i := strings.Index(path, "/-/"); i >= 0 {
+		path = path[:i]
+	}
+	parts, err := splitRepoPath(path)
+	if err != nil {
+		return nil, err
+	}
+	owner := strings.Join(parts[:len(parts)-1], "/")
+	name := parts[len(parts)-1]
+
+	scheme := "https"
+	if host == g.host && strings.HasPrefix(g.baseURL, "http://") {
+		scheme = "http"
+	}
+	return &Repo{
+		Provider: g.Name(),
+		Host:     host,
+		Owner:    owner,
+		Name:     name,
+		CloneURL: fmt.Sprintf("%s://%s/%s/%s.git", scheme, host, owner, name),
+	}, nil
+}
+
+func (g *gitlabProvider) OAuthConfigured() bool {
+	return g.clientID != "" && g.clientSecret != ""
+}
+
+func (g *gitlabProvider) AuthURL(state, codeChallenge string) string {
+	q := url.Values{
+		"client_id":             {g.clientID},
+		"redirect_uri":          {g.redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {"read_user"},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	return g.baseURL + "/oauth/authorize?" + q.Encode()
+}
+
+func (g *gitlabProvider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	if !g.OAuthConfigured() {
+		return "", ErrNotConfigured
+	}
+	return postForm(ctx, g.baseURL+"/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {g.clientID},
+		"client_secret": {g.clientSecret},
+		"code":          {code},
+		"redirect_uri":  {g.redirectURI},
+		"code_verifier": {codeVerifier},
+	}, "", "")
+}
+
+// Identity only trusts the primary email once GitLab has confirmed it.
+func (g *gitlabProvider) Identity(ctx context.Context, accessToken string) (*Identity, error) {
+	var user struct {
+		ID          int64   `json:"id"`
+		Username    string  `json:"username"`
+		Email       string  `json:"email"`
+		ConfirmedAt *string `json:"confirmed_at"`
+	}
+	if err := getJSON(ctx, accessToken, g.baseURL+"/api/v4/user", &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("GitLab user response has no id")
+	}
+
+	email := ""
+	if user.ConfirmedAt != nil && user.Email != "" {
+		email = strings.ToLower(user.Email)
+	}
+	return &Identity{ID: strconv.FormatInt(user.ID, 10), Login: user.Username, Email: email}, nil
+}
+
+// CloneCredentials works for OAuth tokens and personal/project access tokens.
+func (g *gitlabProvider) CloneCredentials(accessToken string) (string, string) {
+	return "oauth2", accessToken
+}
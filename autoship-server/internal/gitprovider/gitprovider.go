@@ -0,0 +1,136 @@
+// Package gitprovider abstracts the Git hosting services Auto-Ship works with
+// (GitHub, GitLab including self-hosted instances, and Bitbucket Cloud) behind
+// a single Provider interface: repository URL parsing, OAuth login and clone
+// credentials.
+//
+// Providers are configured at startup from environment variables (see Init)
+// and looked up by name with Get or by repository URL with ParseRepoURL.
+// URL parsing and cloning work for every provider; OAuth login is only
+// available for providers whose client credentials are set.
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrNotConfigured is returned by OAuth methods of a provider that has no
+// client credentials configured.
+var ErrNotConfigured = errors.New("OAuth is not configured for this provider")
+
+// Repo identifies a repository on a provider.
+type Repo struct {
+	Provider string // provider name, e.g. "gitlab"
+	Host     string // e.g. "github.com" or "gitlab.example.com"
+	Owner    string // user, organization, group ("group/subgroup") or workspace
+	Name     string
+	CloneURL string // canonical https clone URL
+}
+
+// Identity is the account behind an OAuth login.
+type Identity struct {
+	ID    string // stable provider account ID
+	Login string // username; may be renamed, never used as a key
+	Email string // a verified email, or "" if the provider has none
+}
+
+// Provider is one Git hosting service.
+type Provider interface {
+	// Name returns the provider identifier: "github", "gitlab" or "bitbucket".
+	Name() string
+	// MatchesHost reports whether repository URLs on host belong to this provider.
+	MatchesHost(host string) bool
+	// ParseRepoPath builds a Repo from the path of a repository URL on host.
+	ParseRepoPath(host, path string) (*Repo, error)
+
+	// OAuthConfigured reports whether client credentials are set.
+	OAuthConfigured() bool
+	// AuthURL returns the consent page URL for an authorization code flow
+	// with the given state and S256 PKCE challenge.
+	AuthURL(state, codeChallenge string) string
+	// Exchange trades an authorization code for an access token.
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	// Identity fetches the account an access token belongs to.
+	Identity(ctx context.Context, accessToken string) (*Identity, error)
+
+	// CloneCredentials returns the HTTP basic-auth pair git should use to
+	// clone a private repository with accessToken (an OAuth token, personal
+	// access token or app password, depending on the provider).
+	CloneCredentials(accessToken string) (username, password string)
+}
+
+// registry holds the providers set up by Init, in matching order.
+var registry []Provider
+
+// Init configures every provider from the environment. It must be called once
+// at startup before Get or ParseRepoURL.
+func Init() error {
+	gitlab, err := newGitLabProvider()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gitlab provider: %w", err)
+	}
+	registry = []Provider{newGitHubProvider(), gitlab, newBitbucketProvider()}
+	return nil
+}
+
+// Get returns the provider called name.
+func Get(name string) (Provider, bool) {
+	for _, p := range registry {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// ParseRepoURL resolves a repository URL to its provider. It accepts https
+// URLs and scp-style SSH URLs (git@host:owner/repo.git).
+func ParseRepoURL(raw string) (*Repo, Provider, error) {
+	raw = strings.TrimSpace(raw)
+	if rest, ok := strings.CutPrefix(raw, "git@"); ok {
+		host, path, found := strings.Cut(rest, ":")
+		if !found {
+			return nil, nil, fmt.Errorf("invalid repository URL")
+		}
+		raw = "https://" + host + "/" + path
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, nil, fmt.Errorf("invalid repository URL")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, nil, fmt.Errorf("unsupported repository URL scheme %q", u.Scheme)
+	}
+	host := strings.ToLower(u.Host)
+
+	for _, p := range registry {
+		if p.MatchesHost(host) {
+			repo, err := p.ParseRepoPath(host, u.Path)
+			if err != nil {
+				return nil, nil, err
+			}
+			return repo, p, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unsupported Git host %q (supported: GitHub, GitLab, Bitbucket)", host)
+}
+
+// splitRepoPath trims slashes and a ".git" suffix from path and returns its
+// segments, requiring at least two.
+func splitRepoPath(path string) ([]string, error) {
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("repository URL must include owner and repository name")
+	}
+	for _, p := range parts {
+		if p == "" || p == "." || p == ".." {
+			return nil, fmt.Errorf("invalid repository path %q", path)
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,52 @@
+package gitprovider
+
+import "testing"
+
+func TestParseRepoURL(t *testing.T) {
+	t.Setenv("GITLAB_URL", "http://git.example.com/")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    Repo
+		wantErr bool
+	}{
+		{"github https", "https://github.com/octo/app", Repo{"github", "github.com", "octo", "app", "https://github.com/octo/app.git"}, false},
+		{"github .git suffix and slash", " https://www.GitHub.com/octo/app.git/ ", Repo{"github", "github.com", "octo", "app", "https://github.com/octo/app.git"}, false},
+		{"github scp-style", "git@github.com:octo/app.git", Repo{"github", "github.com", "octo", "app", "https://github.com/octo/app.git"}, false},
+		{"github tree URL", "https://github.com/octo/app/tree/main", Repo{"github", "github.com", "octo", "app", "https://github.com/octo/app.git"}, false},
+		{"gitlab nested groups", "https://gitlab.com/group/sub/app.git", Repo{"gitlab", "gitlab.com", "group/sub", "app", "https://gitlab.com/group/sub/app.git"}, false},
+		{"gitlab UI URL", "https://gitlab.com/group/sub/app/-/tree/main", Repo{"gitlab", "gitlab.com", "group/sub", "app", "https://gitlab.com/group/sub/app.git"}, false},
+		{"gitlab scp-style nested", "git@gitlab.com:group/sub/app.git", Repo{"gitlab", "gitlab.com", "group/sub", "app", "https://gitlab.com/group/sub/app.git"}, false},
+		{"self-hosted gitlab keeps scheme", "http://git.example.com/team/app", Repo{"gitlab", "git.example.com", "team", "app", "http://git.example.com/team/app.git"}, false},
+		{"self-hosted gitlab scp-style", "git@git.example.com:team/infra/app.git", Repo{"gitlab", "git.example.com", "team/infra", "app", "http://git.example.com/team/infra/app.git"}, false},
+		{"bitbucket clone URL with user", "https://jane@bitbucket.org/acme/app.git", Repo{"bitbucket", "bitbucket.org", "acme", "app", "https://bitbucket.org/acme/app.git"}, false},
+		{"bitbucket scp-style", "git@bitbucket.org:acme/app.git", Repo{"bitbucket", "bitbucket.org", "acme", "app", "https://bitbucket.org/acme/app.git"}, false},
+		{"unknown host", "https://git.other.com/team/app", Repo{}, true},
+		{"owner only", "https://github.com/octo", Repo{}, true},
+		{"dot-dot segment", "https://gitlab.com/group/../app", Repo{}, true},
+		{"unsupported scheme", "ssh://github.com/octo/app", Repo{}, true},
+		{"scp-style without path", "git@github.com", Repo{}, true},
+		{"no host", "octo/app", Repo{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, p, err := ParseRepoURL(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRepoURL(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != tt.want {
+				t.Errorf("ParseRepoURL(%q) = %+v, want %+v", tt.raw, *got, tt.want)
+			}
+			if p.Name() != tt.want.Provider {
+				t.Errorf("ParseRepoURL(%q) provider = %q, want %q", tt.raw, p.Name(), tt.want.Provider)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package gitprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// postForm posts an OAuth token request and returns the access_token field.
+// Only the provider's error fields are surfaced; the body may otherwise
+// contain credentials.
+func postForm(ctx context.Context, endpoint string, form url.Values, basicUser, basicPass string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	slog.Debug("OAuth token exchange response", "endpoint", endpoint, "status", resp.Status)
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode token response (%s): %w", resp.Status, err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("failed to get access token: %s (%s)", result.Error, result.ErrorDescription)
+	}
+	return result.AccessToken, nil
+}
+
+// getJSON calls an API endpoint with a bearer token and decodes the response.
+func getJSON(ctx context.Context, accessToken, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	slog.Debug("Git provider API response", "endpoint", endpoint, "status", resp.Status)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
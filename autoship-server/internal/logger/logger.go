@@ -0,0 +1,164 @@
+// Package logger configures the process-wide log/slog logger and carries
+// request-scoped loggers (request ID, user ID, deployment ID) through Fiber
+// handlers and context.Context into the services layer.
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// localsKey is the fiber.Ctx Locals key holding the request logger.
+const localsKey = "logger"
+
+// RequestIDHeader is read from incoming requests (so a proxy can supply its
+// own ID) and echoed back on every response.
+const RequestIDHeader = "X-Request-ID"
+
+type ctxKey struct{}
+
+// redacted replaces the value of any attribute whose key looks sensitive.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively against the full attribute key.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"client_secret": true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"cookie":        true,
+	"code":          true,
+	"env_content":   true,
+}
+
+// Init installs a slog default logger. level is one of debug, info, warn,
+// error (default info); format is "json" (default) or "text".
+func Init(level, format string) {
+	slog.SetDefault(slog.New(newHandler(os.Stdout, level, format)))
+}
+
+// Fatal logs msg at error level, with err if it is not nil, and exits with
+// status 1. It is meant for startup failures in main packages.
+func Fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
+}
+
+func newHandler(w io.Writer, level, format string) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:       parseLevel(level),
+		ReplaceAttr: redact,
+	}
+	if strings.EqualFold(format, "text") {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// redact masks secrets and tokens before they reach the output.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if sensitiveKeys[key] || strings.HasSuffix(key, "_secret") || strings.HasSuffix(key, "_token") || strings.Contains(key, "password") {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() == slog.KindString && strings.Contains(a.Value.String(), "Bearer ") {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// Middleware assigns every request an ID, stores a logger carrying it in the
+// fiber context and logs one line per completed request.
+func Middleware(c *fiber.Ctx) error {
+	requestID := c.Get(RequestIDHeader)
+	if requestID == "" || len(requestID) > 64 {
+		requestID = newRequestID()
+	}
+	c.Set(RequestIDHeader, requestID)
+
+	log := slog.Default().With("request_id", requestID)
+	c.Locals(localsKey, log)
+
+	start := time.Now()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	}
+	// Re-read the logger: auth middleware may have added the user ID.
+	FromCtx(c).Info("request completed",
+		"method", c.Method(),
+		"path", c.Path(),
+		"status", status,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"ip", c.IP(),
+	)
+	return err
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FromCtx returns the request logger stored by Middleware, or the default
+// logger outside a request.
+func FromCtx(c *fiber.Ctx) *slog.Logger {
+	if log, ok := c.Locals(localsKey).(*slog.Logger); ok {
+		return log
+	}
+	return slog.Default()
+}
+
+// With adds attributes to the request logger for the rest of the request.
+func With(c *fiber.Ctx, args ...any) *slog.Logger {
+	log := FromCtx(c).With(args...)
+	c.Locals(localsKey, log)
+	return log
+}
+
+// NewContext returns a copy of ctx carrying log, for handing request-scoped
+// fields down into the services layer.
+func NewContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the logger stored by NewContext, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+			return log
+		}
+	}
+	return slog.Default()
+}
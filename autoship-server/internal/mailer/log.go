@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// logMailer does not deliver anything: it logs each message and, when
+// MAILER_FILE is set, appends it to that file so links in verification and
+// reset emails can be followed during local development.
+type logMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newLogMailer() (*logMailer, error) {
+	return &logMailer{path: os.Getenv("MAILER_FILE")}, nil
+}
+
+func (l *logMailer) Name() string { return "log" }
+
+func (l *logMailer) Send(ctx context.Context, msg Message) error {
+	if l.path == "" {
+		// The body carries single-use tokens; only log it when there is no
+		// file to read it from.
+		slog.InfoContext(ctx, "email not sent (log mailer)", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+		return nil
+	}
+	slog.InfoContext(ctx, "email written to file (log mailer)", "to", msg.To, "subject", msg.Subject, "file", l.path)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n----\n\n",
+		time.Now().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	return err
+}
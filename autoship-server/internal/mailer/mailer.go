@@ -0,0 +1,68 @@
+// Package mailer sends the transactional emails Auto-Ship needs (email
+// verification, password resets, organization invites) through a single
+// Mailer interface.
+//
+// The backend is chosen at startup from the MAILER env var (see Init):
+// "smtp" delivers through an SMTP relay, "log" writes messages to the
+// application log and optionally to a file, which is enough for local
+// development. Everything else accesses it via Get().
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers a message or reports why it could not.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+	// Name returns the backend identifier, e.g. "smtp" or "log".
+	Name() string
+}
+
+// active holds the mailer selected by Init.
+var active Mailer
+
+// Init selects the mailer from name (typically the value of MAILER). An empty
+// name defaults to "log" so a fresh checkout runs without an SMTP relay. It
+// must be called once at startup before Get.
+func Init(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = "log"
+	}
+
+	var (
+		m   Mailer
+		err error
+	)
+	switch name {
+	case "smtp":
+		m, err = newSMTPMailer()
+	case "log":
+		m, err = newLogMailer()
+	default:
+		return fmt.Errorf("unknown MAILER %q (supported: smtp, log)", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s mailer: %w", name, err)
+	}
+	active = m
+	return nil
+}
+
+// Get returns the mailer selected by Init. It panics if Init was not called.
+func Get() Mailer {
+	if active == nil {
+		panic("mailer: Get called before Init")
+	}
+	return active
+}
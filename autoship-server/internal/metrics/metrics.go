@@ -0,0 +1,147 @@
+// Package metrics defines the Prometheus instruments exported on /metrics.
+//
+// Counters and histograms are package-level so any layer (api, services,
+// cloud) can record into them without threading a registry around. Values that
+// live elsewhere (port pool in Mongo, running containers in Docker) are read at
+// scrape time through the Register* helpers.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "autoship"
+
+// Deployment outcomes recorded in DeploymentsTotal.
+const (
+	DeploymentStarted   = "started"
+	DeploymentSucceeded = "succeeded"
+	DeploymentFailed    = "failed"
+)
+
+var (
+	// HTTPRequestDuration is labelled by the route template (e.g.
+	// /projects/:id/logs), not the raw path, to keep cardinality bounded.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DeploymentsTotal counts deployments by project type (static/dynamic),
+	// runtime environment (node/python/go, or static) and status.
+	DeploymentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deployments_total",
+		Help:      "Deployments started, succeeded and failed.",
+	}, []string{"project_type", "environment", "status"})
+
+	CloneDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "clone_duration_seconds",
+		Help:      "Time spent in git clone.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	})
+
+	BuildDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "build_duration_seconds",
+		Help:      "Time spent in docker build, by environment.",
+		Buckets:   []float64{5, 10, 20, 30, 60, 120, 240, 480, 900},
+	}, []string{"environment"})
+
+	CloudUploadBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cloud_upload_bytes_total",
+		Help:      "Bytes uploaded to static-site storage, by cloud provider.",
+	}, []string{"provider"})
+
+	CloudUploadErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cloud_upload_errors_total",
+		Help:      "Failed static-site file uploads, by cloud provider.",
+	}, []string{"provider"})
+
+	RateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by a rate limiter, by limiter.",
+	}, []string{"limiter"})
+)
+
+// scrapeTimeout bounds the Mongo/Docker lookups done while serving /metrics.
+const scrapeTimeout = 5 * time.Second
+
+// RegisterPortPool exports autoship_ports{status=...} computed by count on
+// every scrape. count returns the number of port documents per status.
+func RegisterPortPool(count func(ctx context.Context) (map[string]int64, error)) {
+	prometheus.MustRegister(&funcCollector{
+		desc: prometheus.NewDesc(namespace+"_ports", "Host ports in the port pool, by status.", []string{"status"}, nil),
+		collect: func(ctx context.Context, desc *prometheus.Desc, ch chan<- prometheus.Metric) error {
+			counts, err := count(ctx)
+			if err != nil {
+				return err
+			}
+			for status, n := range counts {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(n), status)
+			}
+			return nil
+		},
+	})
+}
+
+// RegisterRunningContainers exports autoship_running_containers computed by
+// count on every scrape.
+func RegisterRunningContainers(count func(ctx context.Context) (int, error)) {
+	prometheus.MustRegister(&funcCollector{
+		desc: prometheus.NewDesc(namespace+"_running_containers", "Running Auto-Ship app containers.", nil, nil),
+		collect: func(ctx context.Context, desc *prometheus.Desc, ch chan<- prometheus.Metric) error {
+			n, err := count(ctx)
+			if err != nil {
+				return err
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(n))
+			return nil
+		},
+	})
+}
+
+// funcCollector adapts a lookup function to prometheus.Collector. A failed
+// lookup is logged and the series is omitted for that scrape.
+type funcCollector struct {
+	desc    *prometheus.Desc
+	collect func(ctx context.Context, desc *prometheus.Desc, ch chan<- prometheus.Metric) error
+}
+
+func (f *funcCollector) Describe(ch chan<- *prometheus.Desc) { ch <- f.desc }
+
+func (f *funcCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	if err := f.collect(ctx, f.desc, ch); err != nil {
+		slog.Warn("metrics collection failed", "metric", f.desc.String(), "error", err)
+	}
+}
+
+// Middleware records HTTPRequestDuration for every request.
+func Middleware(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	}
+	HTTPRequestDuration.
+		WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(status)).
+		Observe(time.Since(start).Seconds())
+	return err
+}
@@ -3,11 +3,18 @@ package middleware
 
 import (
 	"strings"
+	"time"
 
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
+// IsAuthenticated accepts either a JWT access token or a personal API token
+// ("asp_...") as a Bearer credential. Either way the handler finds the caller
+// in c.Locals("user") as *utils.Claims; API tokens are also stored under
+// c.Locals("api_token") so RequireScope can check their scopes.
 func IsAuthenticated(c *fiber.Ctx) error {
 	// Get the Authorization header
 	authHeader := c.Get("Authorization")
@@ -26,18 +33,62 @@ func IsAuthenticated(c *fiber.Ctx) error {
 	}
 
 	tokenString := parts[1]
+	if strings.HasPrefix(tokenString, utils.APITokenPrefix) {
+		return authenticateAPIToken(c, tokenString)
+	}
 
 	// Verify the JWT token
 	claims, err := utils.VerifyJWT(tokenString)
-	if err != nil {
+	if err != nil || claims.ID == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid or expired token",
 		})
 	}
 
+	// Reject tokens revoked by logout
+	revoked, err := db.IsAccessTokenRevoked(c.UserContext(), claims.ID)
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Unable to verify token",
+		})
+	}
+	if revoked {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Token has been revoked",
+		})
+	}
+
 	// Set user info to context
 	c.Locals("user", claims)
+	logger.With(c, "user_id", claims.UserID)
 
 	// Continue
 	return c.Next()
 }
+
+func authenticateAPIToken(c *fiber.Ctx, tokenString string) error {
+	ctx := c.UserContext()
+	token, err := db.FindAPIToken(ctx, utils.HashToken(tokenString))
+	if err != nil || (token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt)) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired token",
+		})
+	}
+
+	user, err := db.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired token",
+		})
+	}
+
+	if err := db.TouchAPIToken(ctx, token.ID); err != nil {
+		logger.FromCtx(c).Warn("failed to update API token last use", "error", err)
+	}
+
+	c.Locals("user", &utils.Claims{UserID: user.ID.Hex(), Email: user.Email})
+	c.Locals("api_token", token)
+	logger.With(c, "user_id", user.ID.Hex(), "api_token_id", token.ID.Hex())
+
+	return c.Next()
+}
@@ -0,0 +1,167 @@
+// internal/middleware/permissions.go
+package middleware
+
+import (
+	"context"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProjectFromCtx returns the project loaded by RequireProjectPermission or
+// RequireContainerPermission.
+func ProjectFromCtx(c *fiber.Ctx) *models.Project {
+	project, _ := c.Locals("project").(*models.Project)
+	return project
+}
+
+// MembershipFromCtx returns the caller's membership loaded by
+// RequireOrgPermission.
+func MembershipFromCtx(c *fiber.Ctx) *models.Membership {
+	m, _ := c.Locals("membership").(*models.Membership)
+	return m
+}
+
+// RequireProjectPermission loads the project named by the :id route parameter
+// and rejects the request unless the caller may perform perm on it: personal
+// projects are reserved to their owner, organization projects follow the
+// caller's role in that organization. The project is stored in
+// c.Locals("project") for the handler. It must run after IsAuthenticated.
+func RequireProjectPermission(perm string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return authorizeProject(c, perm, func(ctx context.Context) (*models.Project, error) {
+			return db.GetProjectByID(ctx, c.Params("id"))
+		})
+	}
+}
+
+// RequireContainerPermission is RequireProjectPermission for routes that
+// address a project by its :containerName. The project ID is accepted there
+// too, which is the only way to address static projects.
+func RequireContainerPermission(perm string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return authorizeProject(c, perm, func(ctx context.Context) (*models.Project, error) {
+			ref := c.Params("containerName")
+			if primitive.IsValidObjectID(ref) {
+				return db.GetProjectByID(ctx, ref)
+			}
+			return db.GetProjectByContainerName(ctx, ref)
+		})
+	}
+}
+
+// authorizeProject answers 404 both for missing projects and for projects the
+// caller has no access to at all, so callers cannot probe for other users'
+// deployments. Organization members whose role lacks perm get 403.
+func authorizeProject(c *fiber.Ctx, perm string, load func(ctx context.Context) (*models.Project, error)) error {
+	claims := c.Locals("user").(*utils.Claims)
+
+	project, err := load(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+	}
+
+	if project.OrgID.IsZero() {
+		if project.OwnerID.IsZero() || project.OwnerID.Hex() != claims.UserID {
+			logger.FromCtx(c).Warn("project access denied", "project_id", project.ID.Hex())
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+		}
+	} else {
+		membership, err := loadMembership(c, project.OrgID, claims.UserID)
+		if err != nil {
+			logger.FromCtx(c).Warn("project access denied", "project_id", project.ID.Hex())
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Project not found"})
+		}
+		if !models.RoleHasPermission(membership.Role, perm) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Your role does not allow " + perm,
+			})
+		}
+		if ok, err := TwoFactorSatisfied(c.UserContext(), membership); err != nil || !ok {
+			return twoFactorDenied(c, membership, err)
+		}
+		c.Locals("membership", membership)
+	}
+
+	c.Locals("project", project)
+	logger.With(c, "project_id", project.ID.Hex())
+	return c.Next()
+}
+
+// RequireOrgPermission rejects the request unless the caller is a member of
+// the organization named by the :orgId route parameter and their role grants
+// perm. An empty perm only requires membership. Non-members get 404. The
+// membership is stored in c.Locals("membership"). It must run after
+// IsAuthenticated.
+func RequireOrgPermission(perm string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("user").(*utils.Claims)
+
+		orgID, err := primitive.ObjectIDFromHex(c.Params("orgId"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		membership, err := loadMembership(c, orgID, claims.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Organization not found"})
+		}
+		if perm != "" && !models.RoleHasPermission(membership.Role, perm) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Your role does not allow " + perm,
+			})
+		}
+		// Membership-only routes (viewing the organization, leaving it) stay
+		// open so members can still see why they are locked out.
+		if perm != "" {
+			if ok, err := TwoFactorSatisfied(c.UserContext(), membership); err != nil || !ok {
+				return twoFactorDenied(c, membership, err)
+			}
+		}
+
+		c.Locals("membership", membership)
+		logger.With(c, "org_id", orgID.Hex())
+		return c.Next()
+	}
+}
+
+// TwoFactorSatisfied reports whether the member may act in their organization
+// under its two-factor requirement: either the organization does not require
+// 2FA or the member has enabled it.
+func TwoFactorSatisfied(ctx context.Context, m *models.Membership) (bool, error) {
+	org, err := db.GetOrg(ctx, m.OrgID)
+	if err != nil {
+		return false, err
+	}
+	if !org.Require2FA {
+		return true, nil
+	}
+	user, err := db.GetUserByID(ctx, m.UserID)
+	if err != nil {
+		return false, err
+	}
+	return user.TOTPEnabled, nil
+}
+
+// twoFactorDenied answers a request from a member locked out by their
+// organization's 2FA requirement, or 503 if the check itself failed.
+func twoFactorDenied(c *fiber.Ctx, m *models.Membership, err error) error {
+	if err != nil {
+		logger.FromCtx(c).Error("failed to check organization 2FA requirement", "org_id", m.OrgID.Hex(), "error", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Authorization temporarily unavailable"})
+	}
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": "This organization requires two-factor authentication; enable it in your account settings",
+	})
+}
+
+func loadMembership(c *fiber.Ctx, orgID primitive.ObjectID, userID string) (*models.Membership, error) {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetMembership(c.UserContext(), orgID, uid)
+}
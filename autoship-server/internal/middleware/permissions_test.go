@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// authorizeApp serves GET / as userID through authorizeProject with load,
+// answering 200 when the handler is reached.
+func authorizeApp(userID string, load func(ctx context.Context) (*models.Project, error)) *fiber.App {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals("user", &utils.Claims{UserID: userID})
+		return c.Next()
+	}, func(c *fiber.Ctx) error {
+		return authorizeProject(c, models.PermProjectsRead, load)
+	}, func(c *fiber.Ctx) error {
+		if ProjectFromCtx(c) == nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("project not stored for the handler")
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestAuthorizeProjectPersonal(t *testing.T) {
+	owner := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+	project := &models.Project{ID: primitive.NewObjectID(), OwnerID: owner}
+	found := func(context.Context) (*models.Project, error) { return project, nil }
+
+	tests := []struct {
+		name   string
+		caller primitive.ObjectID
+		load   func(context.Context) (*models.Project, error)
+		want   int
+	}{
+		{"owner", owner, found, fiber.StatusOK},
+		{"other user", other, found, fiber.StatusNotFound},
+		{"missing project", owner, func(context.Context) (*models.Project, error) {
+			return nil, errors.New("not found")
+		}, fiber.StatusNotFound},
+		{"project without owner", owner, func(context.Context) (*models.Project, error) {
+			return &models.Project{ID: primitive.NewObjectID()}, nil
+		}, fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := authorizeApp(tt.caller.Hex(), tt.load).Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+// TestAuthorizeProjectSameResponse checks that a project the caller may not
+// see is indistinguishable from one that does not exist.
+func TestAuthorizeProjectSameResponse(t *testing.T) {
+	project := &models.Project{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID()}
+	caller := primitive.NewObjectID().Hex()
+
+	bodies := map[string]string{}
+	for name, load := range map[string]func(context.Context) (*models.Project, error){
+		"forbidden": func(context.Context) (*models.Project, error) { return project, nil },
+		"missing":   func(context.Context) (*models.Project, error) { return nil, errors.New("not found") },
+	} {
+		resp, err := authorizeApp(caller, load).Test(httptest.NewRequest("GET", "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies[name] = string(body)
+	}
+	if bodies["forbidden"] != bodies["missing"] {
+		t.Errorf("responses differ: forbidden %q, missing %q", bodies["forbidden"], bodies["missing"])
+	}
+}
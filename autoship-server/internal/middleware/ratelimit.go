@@ -0,0 +1,42 @@
+// internal/middleware/ratelimit.go
+package middleware
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ratelimit"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitByIP limits requests per client IP. Behind a reverse proxy the IP
+// is only meaningful when TRUSTED_PROXIES is set (see main).
+func RateLimitByIP(l *ratelimit.Limiter) fiber.Handler {
+	return rateLimit(l, func(c *fiber.Ctx) string { return c.IP() })
+}
+
+// RateLimitByUser limits requests per authenticated user, whether they use a
+// session or an API token. It must run after IsAuthenticated.
+func RateLimitByUser(l *ratelimit.Limiter) fiber.Handler {
+	return rateLimit(l, func(c *fiber.Ctx) string {
+		return c.Locals("user").(*utils.Claims).UserID
+	})
+}
+
+func rateLimit(l *ratelimit.Limiter, key func(c *fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ok, wait := l.Allow(key(c))
+		if ok {
+			return c.Next()
+		}
+		metrics.RateLimitedTotal.WithLabelValues(l.Name).Inc()
+		logger.FromCtx(c).Warn("rate limit exceeded", "limiter", l.Name)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "Too many requests, please try again later",
+		})
+	}
+}
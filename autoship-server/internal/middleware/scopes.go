@@ -0,0 +1,33 @@
+// internal/middleware/scopes.go
+package middleware
+
+import (
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireScope rejects requests authenticated with a personal API token that
+// was not granted scope. Interactive sessions (JWTs) carry every scope. It must
+// run after IsAuthenticated.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token, ok := c.Locals("api_token").(*models.APIToken); ok && !token.HasScope(scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "API token is missing the " + scope + " scope",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated with a personal API token, for
+// routes such as token management and logout that need an interactive login.
+// It must run after IsAuthenticated.
+func RequireSession(c *fiber.Ctx) error {
+	if _, ok := c.Locals("api_token").(*models.APIToken); ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "This endpoint requires a user session, not an API token",
+		})
+	}
+	return c.Next()
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scopes a personal API token can be granted.
+const (
+	ScopeProjectsRead   = "projects:read"
+	ScopeProjectsDeploy = "projects:deploy"
+	ScopeProjectsDelete = "projects:delete"
+)
+
+// APITokenScopes lists every valid scope, in display order.
+var APITokenScopes = []string{ScopeProjectsRead, ScopeProjectsDeploy, ScopeProjectsDelete}
+
+// APIToken is a named, scoped personal access token for CI and CLI use. The
+// token value ("asp_...") is shown once at creation; only its SHA-256 is
+// stored. Prefix keeps the first few characters so users can tell tokens apart.
+type APIToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"-"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// HasScope reports whether the token was granted scope.
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
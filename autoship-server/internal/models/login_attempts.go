@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LoginAttempts counts consecutive failed logins for one account key (an
+// email address, or "2fa:<user id>" for second-factor codes). LockedUntil
+// is pushed out as failures accumulate; a successful login deletes the
+// document.
+type LoginAttempts struct {
+	Key         string     `bson:"key" json:"key"`
+	Failures    int        `bson:"failures" json:"failures"`
+	LockedUntil *time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	UpdatedAt   time.Time  `bson:"updated_at" json:"updated_at"`
+}
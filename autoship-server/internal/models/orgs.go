@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Organization members' roles, from most to least privileged.
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleDeveloper = "developer"
+	RoleViewer    = "viewer"
+)
+
+// Permissions checked by middleware.RequireOrgPermission and
+// middleware.RequireProjectPermission. The project permissions share their
+// names with the API token scopes.
+const (
+	PermProjectsRead   = ScopeProjectsRead
+	PermProjectsDeploy = ScopeProjectsDeploy
+	PermProjectsDelete = ScopeProjectsDelete
+	PermMembersManage  = "members:manage"
+	PermOrgSettings    = "org:settings"
+)
+
+// rolePermissions maps each role to what it may do inside its organization.
+var rolePermissions = map[string][]string{
+	RoleOwner:     {PermProjectsRead, PermProjectsDeploy, PermProjectsDelete, PermMembersManage, PermOrgSettings},
+	RoleAdmin:     {PermProjectsRead, PermProjectsDeploy, PermProjectsDelete, PermMembersManage, PermOrgSettings},
+	RoleDeveloper: {PermProjectsRead, PermProjectsDeploy},
+	RoleViewer:    {PermProjectsRead},
+}
+
+// ValidRole reports whether role is one of the organization roles.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission reports whether role grants perm.
+func RoleHasPermission(role, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Organization groups users who share projects.
+type Organization struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Slug      string             `bson:"slug" json:"slug"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	// Require2FA denies members without two-factor authentication access to
+	// the organization's projects and management routes.
+	Require2FA bool `bson:"require_2fa" json:"require_2fa"`
+	// Plan and Quota work as on models.User, for the organization's projects.
+	Plan      string    `bson:"plan,omitempty" json:"plan,omitempty"`
+	Quota     *Quota    `bson:"quota,omitempty" json:"quota,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Membership gives a user a role in an organization.
+type Membership struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID     primitive.ObjectID `bson:"org_id" json:"org_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role      string             `bson:"role" json:"role"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Member is a Membership joined with the member's email, for listings.
+type Member struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Email    string             `bson:"email" json:"email"`
+	Role     string             `bson:"role" json:"role"`
+	JoinedAt time.Time          `bson:"created_at" json:"joined_at"`
+	TOTP     bool               `bson:"totp_enabled" json:"totp_enabled"`
+}
+
+// Invite lets whoever holds its token join an organization with Role, as long
+// as they are signed in with the invited Email. Only the token's SHA-256 is
+// stored.
+type Invite struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrgID      primitive.ObjectID `bson:"org_id" json:"org_id"`
+	Email      string             `bson:"email" json:"email"`
+	Role       string             `bson:"role" json:"role"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	InvitedBy  primitive.ObjectID `bson:"invited_by" json:"invited_by"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	AcceptedAt *time.Time         `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -1,16 +1,11 @@
 package models
 
-import (
-	"time"
+import "time"
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
-)
-
-type PortMapping struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty"` // MongoDB document ID
-	Port          int                `bson:"port"`          // Host port on EC2
-	ContainerPort int                `bson:"containerPort"` // Port exposed inside the Docker container
-	Status        string             `bson:"status"`        // "available" or "used"
-	ContainerName string             `bson:"containerName"` // Docker container name
-	Timestamp     time.Time          `bson:"timestamp"`     // Timestamp of allocation or update
+// PortLease reserves a host port for one container. A port is free exactly
+// when it has no lease; releasing a port deletes its document.
+type PortLease struct {
+	Port          int       `bson:"port" json:"port"`
+	ContainerName string    `bson:"container_name" json:"container_name"`
+	LeasedAt      time.Time `bson:"leased_at" json:"leased_at"`
 }
@@ -2,20 +2,59 @@ package models
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
 	"time"
 )
 
+// Project statuses. Only dynamic projects are ever stopped; documents written
+// before statuses existed have none and are running. A project is deleting
+// from the first teardown attempt until its document is removed.
+const (
+	ProjectStatusRunning  = "running"
+	ProjectStatusStopped  = "stopped"
+	ProjectStatusDeleting = "deleting"
+)
+
+// TeardownStep is the outcome of one step of deleting a project.
+type TeardownStep struct {
+	Done  bool      `bson:"done" json:"done"`
+	Error string    `bson:"error,omitempty" json:"error,omitempty"`
+	At    time.Time `bson:"at" json:"at"`
+}
+
 type Project struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username      string             `bson:"username" json:"username"`
-	RepoURL       string             `bson:"repo_url" json:"repo_url"`
-	RepoName      string             `bson:"repo_name" json:"repo_name"`
-	ProjectType   string             `bson:"project_type" json:"project_type"`
-	HostedURL     string             `bson:"hosted_url" json:"hosted_url"`
-	StartCommand  string             `bson:"start_command" json:"start_command"`
-	ContainerPort int                `bson:"container_port" json:"container_port"`
-	HostPort      int                `bson:"host_port" json:"host_port"`
-	ContainerName string             `bson:"container_name" json:"container_name"`
-	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	ID            primitive.ObjectID      `bson:"_id,omitempty" json:"id"`
+	OwnerID       primitive.ObjectID      `bson:"owner_id" json:"owner_id"`                 // Auto-Ship user who deployed it; Username is the repo's GitHub owner
+	OrgID         primitive.ObjectID      `bson:"org_id,omitempty" json:"org_id,omitempty"` // set for organization projects; access then follows org roles
+	Username      string                  `bson:"username" json:"username"`
+	RepoURL       string                  `bson:"repo_url" json:"repo_url"`
+	RepoName      string                  `bson:"repo_name" json:"repo_name"`
+	Branch        string                  `bson:"branch,omitempty" json:"branch,omitempty"` // empty means the repository's default branch
+	ProjectType   string                  `bson:"project_type" json:"project_type"`
+	HostedURL     string                  `bson:"hosted_url" json:"hosted_url"`
+	Subdomain     string                  `bson:"subdomain,omitempty" json:"subdomain,omitempty"` // served by the deploy agent (dynamic projects)
+	StartCommand  string                  `bson:"start_command" json:"start_command"`
+	ContainerPort int                     `bson:"container_port" json:"container_port"`
+	HostPort      int                     `bson:"host_port" json:"host_port"`
+	ContainerName string                  `bson:"container_name" json:"container_name"`
+	Status        string                  `bson:"status,omitempty" json:"status,omitempty"`
+	EnvContent    string                  `bson:"env_content,omitempty" json:"-"`                         // .env written into redeploys; never returned
+	MemoryMB      int                     `bson:"memory_mb,omitempty" json:"memory_mb,omitempty"`         // container memory limit (dynamic projects)
+	StorageBytes  int64                   `bson:"storage_bytes,omitempty" json:"storage_bytes,omitempty"` // uploaded site size (static projects)
+	StoragePrefix string                  `bson:"storage_prefix,omitempty" json:"-"`                      // object-key prefix of the uploaded site; empty for sites uploaded under <username>/<repo_name>
+	BuildLogPath  string                  `bson:"build_log_path" json:"-"`
+	Teardown      map[string]TeardownStep `bson:"teardown,omitempty" json:"teardown,omitempty"` // by step name, while deleting
+	CreatedAt     time.Time               `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time               `bson:"updated_at" json:"updated_at"`
 }
+
+// Stopped reports whether the project's container has been stopped.
+func (p *Project) Stopped() bool { return p.Status == ProjectStatusStopped }
+
+// Deleting reports whether the project's deletion has started.
+func (p *Project) Deleting() bool { return p.Status == ProjectStatusDeleting }
+
+// Container is the name of the project's Docker container, which also names
+// its image, port lease and build log directory. Older projects stored
+// ContainerName with the repo owner's casing; Docker always got it lowercased.
+func (p *Project) Container() string { return strings.ToLower(p.ContainerName) }
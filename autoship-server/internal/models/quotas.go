@@ -0,0 +1,48 @@
+package models
+
+// Plans users and organizations can be on. Operators assign them directly in
+// Mongo (users.plan / organizations.plan); accounts without one get the
+// DEFAULT_PLAN.
+const (
+	PlanFree      = "free"
+	PlanPro       = "pro"
+	PlanUnlimited = "unlimited"
+)
+
+// Quota caps the resources one account (a user's personal projects, or an
+// organization) may use. A zero field means no limit.
+type Quota struct {
+	MaxProjects           int   `bson:"max_projects" json:"maxProjects"`
+	MaxConcurrentBuilds   int   `bson:"max_concurrent_builds" json:"maxConcurrentBuilds"`
+	MaxRunningContainers  int   `bson:"max_running_containers" json:"maxRunningContainers"`
+	MaxStaticStorageBytes int64 `bson:"max_static_storage_bytes" json:"maxStaticStorageBytes"`
+	MaxMemoryMB           int   `bson:"max_memory_mb" json:"maxMemoryMb"` // summed over running containers
+}
+
+// Plans holds the quota of each plan.
+var Plans = map[string]Quota{
+	PlanFree: {
+		MaxProjects:           5,
+		MaxConcurrentBuilds:   1,
+		MaxRunningContainers:  2,
+		MaxStaticStorageBytes: 100 << 20,
+		MaxMemoryMB:           1024,
+	},
+	PlanPro: {
+		MaxProjects:           50,
+		MaxConcurrentBuilds:   3,
+		MaxRunningContainers:  10,
+		MaxStaticStorageBytes: 5 << 30,
+		MaxMemoryMB:           8192,
+	},
+	PlanUnlimited: {},
+}
+
+// Usage is what an account currently consumes, in the units of Quota.
+type Usage struct {
+	Projects           int   `json:"projects"`
+	ConcurrentBuilds   int   `json:"concurrentBuilds"`
+	RunningContainers  int   `json:"runningContainers"`
+	StaticStorageBytes int64 `json:"staticStorageBytes"`
+	MemoryMB           int   `json:"memoryMb"`
+}
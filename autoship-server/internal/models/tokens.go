@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a long-lived, single-use credential exchanged for a new
+// access token at POST /auth/refresh. Only the SHA-256 of the token is
+// stored. Every rotation keeps the FamilyID of the original login so that
+// reuse of an already-rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	FamilyID  string             `bson:"family_id" json:"family_id"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// RevokedToken denylists an access token by its jti until it would have
+// expired anyway; a TTL index removes the entry after that.
+type RevokedToken struct {
+	JTI       string    `bson:"jti" json:"jti"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
@@ -6,9 +6,25 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email     string             `bson:"email" json:"email"`
-	Password  string             `bson:"password" json:"password"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email      string             `bson:"email" json:"email"`
+	Password   string             `bson:"password" json:"password"`
+	Identities map[string]string  `bson:"identities,omitempty" json:"identities,omitempty"` // login provider ("github", "gitlab", ...) -> linked account ID
+	// EmailVerified is set once the user followed a verification link or
+	// signed up through a provider that vouches for the address.
+	EmailVerified   bool       `bson:"email_verified" json:"email_verified"`
+	EmailVerifiedAt *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	// TOTPSecret is set when enrolment starts; TOTPEnabled once the user has
+	// confirmed a code from their authenticator app. TOTPLastStep is the last
+	// accepted time step, which keeps codes from being replayed.
+	TOTPSecret    string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPEnabled   bool     `bson:"totp_enabled" json:"totp_enabled"`
+	TOTPLastStep  int64    `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodes []string `bson:"recovery_codes,omitempty" json:"-"` // SHA-256 of unused recovery codes
+	// Plan selects the quota of the user's personal projects; Quota, when
+	// set, overrides it for this user only.
+	Plan      string    `bson:"plan,omitempty" json:"plan,omitempty"`
+	Quota     *Quota    `bson:"quota,omitempty" json:"quota,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 }
@@ -0,0 +1,189 @@
+// Package ports leases host ports to app containers.
+//
+// Every leased port has one document in the "ports" collection, with a unique
+// index on port: a port is claimed by inserting its lease, so two deployments
+// can never get the same one, and freed by deleting it. Lease hands out the
+// lowest free port of PORT_RANGE, so released ports are reused. A periodic
+// sweep frees leases whose containers are gone. Ports recorded on projects are
+// never handed out either: projects deployed before leases existed have none,
+// and a stopped container does not bind its port.
+package ports
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
+)
+
+var (
+	rangeStart = 2000
+	rangeEnd   = 65535
+)
+
+// Init reads PORT_RANGE ("first-last", default 2000-65535). It must be called
+// once at startup.
+func Init() error {
+	v := os.Getenv("PORT_RANGE")
+	if v == "" {
+		return nil
+	}
+	first, last, ok := strings.Cut(v, "-")
+	start, err1 := strconv.Atoi(strings.TrimSpace(first))
+	end, err2 := strconv.Atoi(strings.TrimSpace(last))
+	if !ok || err1 != nil || err2 != nil || start < 1024 || end > 65535 || start > end {
+		return fmt.Errorf("invalid PORT_RANGE %q (expected first-last within 1024-65535)", v)
+	}
+	rangeStart, rangeEnd = start, end
+	return nil
+}
+
+// taken returns the ports that are leased or recorded on a project.
+func taken(ctx context.Context) (map[int]bool, error) {
+	leases, err := db.ListPortLeases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list port leases: %w", err)
+	}
+	projectPorts, err := db.ListProjectHostPorts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project ports: %w", err)
+	}
+	ports := make(map[int]bool, len(leases)+len(projectPorts))
+	for _, l := range leases {
+		ports[l.Port] = true
+	}
+	for _, p := range projectPorts {
+		ports[p] = true
+	}
+	return ports, nil
+}
+
+// Lease claims the lowest port of the range that is neither taken nor in use
+// on this host, for containerName.
+func Lease(ctx context.Context, containerName string) (int, error) {
+	used, err := taken(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for port := rangeStart; port <= rangeEnd; port++ {
+		if used[port] || !utils.IsPortAvailable(port) {
+			continue
+		}
+		ok, err := db.InsertPortLease(ctx, port, containerName)
+		if err != nil {
+			return 0, fmt.Errorf("failed to lease port %d: %w", port, err)
+		}
+		if ok {
+			slog.Info("leased host port", "port", port, "container", containerName)
+			return port, nil
+		}
+		// Leased by a concurrent deployment since the listing; try the next.
+	}
+	return 0, fmt.Errorf("no free ports in range %d-%d", rangeStart, rangeEnd)
+}
+
+// Release frees port if it is still leased to containerName. Releasing a
+// port that is not leased is a no-op.
+func Release(ctx context.Context, port int, containerName string) error {
+	if port == 0 {
+		return nil
+	}
+	return db.DeletePortLease(ctx, port, containerName)
+}
+
+// Leased returns every leased port.
+func Leased(ctx context.Context) ([]int, error) {
+	leases, err := db.ListPortLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]int, 0, len(leases))
+	for _, l := range leases {
+		ports = append(ports, l.Port)
+	}
+	return ports, nil
+}
+
+// Counts returns how many ports of the range are "used" (leased or held by a
+// project) and "available", for the autoship_ports metric.
+func Counts(ctx context.Context) (map[string]int64, error) {
+	ports, err := taken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var used int64
+	for port := range ports {
+		if port >= rangeStart && port <= rangeEnd {
+			used++
+		}
+	}
+	return map[string]int64{
+		"used":      used,
+		"available": int64(rangeEnd-rangeStart+1) - used,
+	}, nil
+}
+
+const (
+	// SweepInterval is how often StartSweeper looks for stale leases.
+	SweepInterval = 10 * time.Minute
+	// sweepGrace protects fresh leases: the container is started only
+	// after its port has been leased.
+	sweepGrace = 10 * time.Minute
+)
+
+// Sweep releases the leases whose container no longer exists and whose port
+// no project records. containers returns the names of every container on
+// the host, running or stopped. It returns the number of ports freed.
+func Sweep(ctx context.Context, containers func(ctx context.Context) (map[string]bool, error)) (int, error) {
+	leases, err := db.ListPortLeases(ctx)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := containers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	projectPorts, err := db.ListProjectHostPorts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	inUse := make(map[int]bool, len(projectPorts))
+	for _, p := range projectPorts {
+		inUse[p] = true
+	}
+
+	freed := 0
+	for _, l := range leases {
+		if time.Since(l.LeasedAt) < sweepGrace || existing[l.ContainerName] || inUse[l.Port] {
+			continue
+		}
+		if err := db.DeletePortLease(ctx, l.Port, l.ContainerName); err != nil {
+			return freed, err
+		}
+		slog.Info("released stale port lease", "port", l.Port, "container", l.ContainerName)
+		freed++
+	}
+	return freed, nil
+}
+
+// StartSweeper runs Sweep every SweepInterval in the background.
+func StartSweeper(containers func(ctx context.Context) (map[string]bool, error)) {
+	go func() {
+		ticker := time.NewTicker(SweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := Sweep(ctx, containers); err != nil {
+				slog.Warn("port lease sweep failed", "error", err)
+			}
+			cancel()
+		}
+	}()
+}
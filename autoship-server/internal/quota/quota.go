@@ -0,0 +1,327 @@
+// Package quota enforces the per-account resource limits of models.Plans:
+// projects, concurrent builds, running containers, static storage and
+// container memory.
+//
+// An account is either a user's personal projects or an organization. Usage
+// is derived from the account's project documents plus what deployments in
+// progress hold in memory: build slots claimed by StartBuild and the
+// projects, containers, memory and storage reserved by the Reserve
+// functions. A reservation is checked and taken in one step under a
+// per-account lock, so concurrent deployments cannot all pass the same
+// remaining quota; it is released once the deployment's project document
+// records the usage, or the deployment failed.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Resources named in ExceededError.
+const (
+	ResourceProjects          = "projects"
+	ResourceConcurrentBuilds  = "concurrent_builds"
+	ResourceRunningContainers = "running_containers"
+	ResourceStaticStorage     = "static_storage_bytes"
+	ResourceMemory            = "memory_mb"
+)
+
+// ExceededError reports which limit a deployment would exceed.
+type ExceededError struct {
+	Resource  string
+	Limit     int64
+	Used      int64
+	Requested int64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded for %s: %d used + %d requested > limit %d", e.Resource, e.Used, e.Requested, e.Limit)
+}
+
+// Transient reports whether the limit frees up on its own (a build finishing)
+// rather than needing the user to remove something or upgrade.
+func (e *ExceededError) Transient() bool { return e.Resource == ResourceConcurrentBuilds }
+
+var (
+	defaultPlan     = models.PlanFree
+	defaultMemoryMB = 512
+)
+
+// Init reads DEFAULT_PLAN and DEFAULT_CONTAINER_MEMORY_MB. It must be called
+// once at startup.
+func Init() error {
+	if v := os.Getenv("DEFAULT_PLAN"); v != "" {
+		if _, ok := models.Plans[v]; !ok {
+			return fmt.Errorf("unknown DEFAULT_PLAN %q", v)
+		}
+		defaultPlan = v
+	}
+	if v := os.Getenv("DEFAULT_CONTAINER_MEMORY_MB"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil || mb < 64 {
+			return fmt.Errorf("invalid DEFAULT_CONTAINER_MEMORY_MB %q (minimum 64)", v)
+		}
+		defaultMemoryMB = mb
+	}
+	return nil
+}
+
+// DefaultMemoryMB is the memory limit of containers deployed without one.
+func DefaultMemoryMB() int { return defaultMemoryMB }
+
+// Account identifies whose quota a project counts against: the organization
+// when OrgID is set, the user otherwise.
+type Account struct {
+	UserID primitive.ObjectID
+	OrgID  primitive.ObjectID
+}
+
+func (a Account) key() string {
+	if !a.OrgID.IsZero() {
+		return "org:" + a.OrgID.Hex()
+	}
+	return "user:" + a.UserID.Hex()
+}
+
+// Limits returns the account's plan and effective quota.
+func Limits(ctx context.Context, a Account) (string, models.Quota, error) {
+	var plan string
+	var override *models.Quota
+	if !a.OrgID.IsZero() {
+		org, err := db.GetOrg(ctx, a.OrgID)
+		if err != nil {
+			return "", models.Quota{}, err
+		}
+		plan, override = org.Plan, org.Quota
+	} else {
+		user, err := db.GetUserByID(ctx, a.UserID)
+		if err != nil {
+			return "", models.Quota{}, err
+		}
+		plan, override = user.Plan, user.Quota
+	}
+
+	if _, ok := models.Plans[plan]; !ok {
+		plan = defaultPlan
+	}
+	if override != nil {
+		return plan, *override, nil
+	}
+	return plan, models.Plans[plan], nil
+}
+
+// Usage returns what the account currently uses, including reservations.
+func Usage(ctx context.Context, a Account) (models.Usage, error) {
+	// Reservations are read first: one released in between has had its
+	// usage saved to a project document by then, so it is counted at least
+	// once.
+	held := reserved(a)
+
+	var (
+		projects []models.Project
+		err      error
+	)
+	if !a.OrgID.IsZero() {
+		projects, err = db.ListProjectsByOrg(ctx, a.OrgID)
+	} else {
+		projects, err = db.ListProjectsByOwner(ctx, a.UserID)
+	}
+	if err != nil {
+		return models.Usage{}, err
+	}
+
+	u := models.Usage{Projects: len(projects), ConcurrentBuilds: buildsInProgress(a)}
+	u.Projects += held.Projects
+	u.RunningContainers += held.RunningContainers
+	u.MemoryMB += held.MemoryMB
+	u.StaticStorageBytes += held.StaticStorageBytes
+	for _, p := range projects {
+		switch p.ProjectType {
+		case "dynamic":
+			// A stopped container holds no memory and can be started again
+			// once there is room.
+			if p.Stopped() {
+				continue
+			}
+			u.RunningContainers++
+			if p.MemoryMB > 0 {
+				u.MemoryMB += p.MemoryMB
+			} else {
+				// Deployed before memory limits; count what it would get now.
+				u.MemoryMB += defaultMemoryMB
+			}
+		case "static":
+			u.StaticStorageBytes += p.StorageBytes
+		}
+	}
+	return u, nil
+}
+
+// check returns an ExceededError if used+requested goes over a non-zero limit.
+func check(resource string, limit, used, requested int64) error {
+	if limit > 0 && used+requested > limit {
+		return &ExceededError{Resource: resource, Limit: limit, Used: used, Requested: requested}
+	}
+	return nil
+}
+
+// ReserveNewProject reserves one more project for the account. The caller
+// must call release once the project is saved or the deployment failed.
+func ReserveNewProject(ctx context.Context, a Account) (release func(), err error) {
+	return reserve(ctx, a, models.Usage{Projects: 1})
+}
+
+// ReserveContainer reserves one more running container with memoryMB of
+// memory, released like ReserveNewProject.
+func ReserveContainer(ctx context.Context, a Account, memoryMB int) (release func(), err error) {
+	return reserve(ctx, a, models.Usage{RunningContainers: 1, MemoryMB: memoryMB})
+}
+
+// ReserveMemoryChange reserves the memory for a running container's limit to
+// go from fromMB to toMB. Lowering it is always allowed.
+func ReserveMemoryChange(ctx context.Context, a Account, fromMB, toMB int) (release func(), err error) {
+	if toMB <= fromMB {
+		return func() {}, nil
+	}
+	return reserve(ctx, a, models.Usage{MemoryMB: toMB - fromMB})
+}
+
+// ReserveStaticStorage reserves size more bytes of static storage. A
+// negative size (a redeploy shrinking its site) always fits.
+func ReserveStaticStorage(ctx context.Context, a Account, size int64) (release func(), err error) {
+	if size <= 0 {
+		return func() {}, nil
+	}
+	return reserve(ctx, a, models.Usage{StaticStorageBytes: size})
+}
+
+// reservations holds the usage reserved per account, and a lock per account
+// that makes checking and reserving one step.
+var reservations = struct {
+	sync.Mutex
+	held  map[string]models.Usage
+	locks map[string]*sync.Mutex
+}{held: map[string]models.Usage{}, locks: map[string]*sync.Mutex{}}
+
+func reserved(a Account) models.Usage {
+	reservations.Lock()
+	defer reservations.Unlock()
+	return reservations.held[a.key()]
+}
+
+func accountLock(key string) *sync.Mutex {
+	reservations.Lock()
+	defer reservations.Unlock()
+	l, ok := reservations.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		reservations.locks[key] = l
+	}
+	return l
+}
+
+// reserve checks want against the account's remaining quota and holds it.
+func reserve(ctx context.Context, a Account, want models.Usage) (release func(), err error) {
+	key := a.key()
+	lock := accountLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	_, q, err := Limits(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	u, err := Usage(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range []struct {
+		resource               string
+		limit, used, requested int64
+	}{
+		{ResourceProjects, int64(q.MaxProjects), int64(u.Projects), int64(want.Projects)},
+		{ResourceRunningContainers, int64(q.MaxRunningContainers), int64(u.RunningContainers), int64(want.RunningContainers)},
+		{ResourceMemory, int64(q.MaxMemoryMB), int64(u.MemoryMB), int64(want.MemoryMB)},
+		{ResourceStaticStorage, q.MaxStaticStorageBytes, u.StaticStorageBytes, want.StaticStorageBytes},
+	} {
+		if c.requested == 0 {
+			continue
+		}
+		if err := check(c.resource, c.limit, c.used, c.requested); err != nil {
+			return nil, err
+		}
+	}
+
+	reservations.Lock()
+	reservations.held[key] = addUsage(reservations.held[key], want, 1)
+	reservations.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			reservations.Lock()
+			defer reservations.Unlock()
+			if h := addUsage(reservations.held[key], want, -1); h == (models.Usage{}) {
+				delete(reservations.held, key)
+			} else {
+				reservations.held[key] = h
+			}
+		})
+	}, nil
+}
+
+// addUsage returns u plus sign times the reservable fields of d.
+func addUsage(u, d models.Usage, sign int) models.Usage {
+	u.Projects += sign * d.Projects
+	u.RunningContainers += sign * d.RunningContainers
+	u.MemoryMB += sign * d.MemoryMB
+	u.StaticStorageBytes += int64(sign) * d.StaticStorageBytes
+	return u
+}
+
+// builds counts builds in progress per account.
+var builds = struct {
+	sync.Mutex
+	n map[string]int
+}{n: map[string]int{}}
+
+func buildsInProgress(a Account) int {
+	builds.Lock()
+	defer builds.Unlock()
+	return builds.n[a.key()]
+}
+
+// StartBuild claims one of the account's concurrent build slots. The caller
+// must call release once the deployment has finished, successfully or not.
+func StartBuild(ctx context.Context, a Account) (release func(), err error) {
+	_, q, err := Limits(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	key := a.key()
+	builds.Lock()
+	defer builds.Unlock()
+	if err := check(ResourceConcurrentBuilds, int64(q.MaxConcurrentBuilds), int64(builds.n[key]), 1); err != nil {
+		return nil, err
+	}
+	builds.n[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			builds.Lock()
+			defer builds.Unlock()
+			if builds.n[key]--; builds.n[key] <= 0 {
+				delete(builds.n, key)
+			}
+		})
+	}, nil
+}
@@ -0,0 +1,113 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/db"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var connectOnce sync.Once
+
+// testAccount saves a user with quota q in the MongoDB named by
+// AUTOSHIP_TEST_MONGO_URI, or skips the test.
+func testAccount(t *testing.T, q models.Quota) Account {
+	t.Helper()
+	uri := os.Getenv("AUTOSHIP_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("AUTOSHIP_TEST_MONGO_URI not set")
+	}
+	var err error
+	connectOnce.Do(func() {
+		db.SetMongoURI(uri)
+		err = db.Connect()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{
+		Email:     "quota-" + primitive.NewObjectID().Hex() + "@example.com",
+		Quota:     &q,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.GetCollection("users").DeleteOne(context.Background(), bson.M{"_id": user.ID})
+	})
+	return Account{UserID: user.ID}
+}
+
+// reserveConcurrently runs n reservations at once and returns the releases
+// of those that succeeded.
+func reserveConcurrently(t *testing.T, n int, reserve func() (func(), error)) []func() {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		releases []func()
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := reserve()
+			var exceeded *ExceededError
+			if err != nil && !errors.As(err, &exceeded) {
+				t.Error(err)
+			}
+			if err == nil {
+				mu.Lock()
+				releases = append(releases, release)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return releases
+}
+
+func TestConcurrentReservationsStayWithinQuota(t *testing.T) {
+	a := testAccount(t, models.Quota{MaxProjects: 2, MaxRunningContainers: 3, MaxMemoryMB: 1024})
+	ctx := context.Background()
+
+	projects := reserveConcurrently(t, 10, func() (func(), error) { return ReserveNewProject(ctx, a) })
+	if len(projects) != 2 {
+		t.Errorf("%d of 10 concurrent project reservations succeeded, want 2", len(projects))
+	}
+	// 512 MB each: memory runs out after two containers.
+	containers := reserveConcurrently(t, 10, func() (func(), error) { return ReserveContainer(ctx, a, 512) })
+	if len(containers) != 2 {
+		t.Errorf("%d of 10 concurrent container reservations succeeded, want 2", len(containers))
+	}
+
+	u, err := Usage(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Projects != 2 || u.RunningContainers != 2 || u.MemoryMB != 1024 {
+		t.Errorf("Usage with reservations = %+v, want 2 projects, 2 containers, 1024 MB", u)
+	}
+
+	for _, release := range append(projects, containers...) {
+		release()
+		release() // releasing twice is harmless
+	}
+	if u, err := Usage(ctx, a); err != nil || u != (models.Usage{}) {
+		t.Errorf("Usage after release = %+v, %v; want zero", u, err)
+	}
+	release, err := ReserveNewProject(ctx, a)
+	if err != nil {
+		t.Fatalf("ReserveNewProject after release: %v", err)
+	}
+	release()
+}
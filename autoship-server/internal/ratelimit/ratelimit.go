@@ -0,0 +1,144 @@
+// Package ratelimit implements in-memory token-bucket rate limiting.
+//
+// Buckets live in the server process, which is enough for the single-instance
+// deployments Auto-Ship targets; limits are per instance if it is scaled out.
+// The named limiters used by the API are configured from the environment at
+// startup (see Init).
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a number of events allowed per period, e.g. "20/1m".
+type Rate struct {
+	Count  int
+	Period time.Duration
+}
+
+func (r Rate) String() string { return fmt.Sprintf("%d/%s", r.Count, r.Period) }
+
+// ParseRate parses "<count>/<duration>", e.g. "10/1h" or "5/30s".
+func ParseRate(s string) (Rate, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q, want <count>/<duration>", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate count in %q", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate period in %q", s)
+	}
+	return Rate{Count: n, Period: d}, nil
+}
+
+// Limiter hands out one token bucket per key. Each bucket holds up to
+// rate.Count tokens and refills evenly over rate.Period, so bursts up to the
+// full count are allowed after a quiet period.
+type Limiter struct {
+	Name string
+	rate Rate
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter for rate. name labels it in logs and metrics.
+func New(name string, rate Rate) *Limiter {
+	return &Limiter{Name: name, rate: rate, buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns
+// false and how long until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	perToken := l.rate.Period / time.Duration(l.rate.Count)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Count), last: now}
+		l.buckets[key] = b
+	} else {
+		refill := float64(now.Sub(b.last)) / float64(perToken)
+		b.tokens = math.Min(float64(l.rate.Count), b.tokens+refill)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(perToken))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops buckets that have been idle long enough to be full again, at
+// most once per period, so memory stays proportional to active clients.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Period {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.rate.Period {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Limiters used by the API, set by Init.
+var (
+	// Auth limits unauthenticated auth endpoints (login, signup, password
+	// reset, 2FA) per client IP.
+	Auth *Limiter
+	// Deploy limits deployments per user.
+	Deploy *Limiter
+)
+
+// Init configures the API limiters from RATE_LIMIT_AUTH and
+// RATE_LIMIT_DEPLOY, falling back to the defaults. It must be called once at
+// startup before the routes are registered.
+func Init() error {
+	auth, err := rateFromEnv("RATE_LIMIT_AUTH", "20/1m")
+	if err != nil {
+		return err
+	}
+	deploy, err := rateFromEnv("RATE_LIMIT_DEPLOY", "10/1h")
+	if err != nil {
+		return err
+	}
+	Auth = New("auth", auth)
+	Deploy = New("deploy", deploy)
+	return nil
+}
+
+func rateFromEnv(key, fallback string) (Rate, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		v = fallback
+	}
+	r, err := ParseRate(v)
+	if err != nil {
+		return Rate{}, fmt.Errorf("%s: %w", key, err)
+	}
+	return r, nil
+}
@@ -3,7 +3,7 @@ package services
 
 import (
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	// "strings"
@@ -14,10 +14,9 @@ func DetectProjectType(projectPath string) string {
 	hasFiles := false
 	entries, err := os.ReadDir(projectPath)
 	if err != nil || len(entries) == 0 {
-		fmt.Println("Project path is empty or cannot be read:", projectPath)
+		slog.Warn("project path is empty or cannot be read", "path", projectPath)
 		return "unknown"
 	}
-	fmt.Println("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa:", len(entries))
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			hasFiles = true
@@ -25,7 +24,7 @@ func DetectProjectType(projectPath string) string {
 		}
 	}
 	if !hasFiles {
-		fmt.Println("Project path is empty (no files):", projectPath)
+		slog.Warn("project path has no files", "path", projectPath)
 		return "unknown"
 	}
 	commonDirs := []string{
@@ -35,11 +34,10 @@ func DetectProjectType(projectPath string) string {
 		"dist",     // production build
 		"build",    // output folder
 	}
-	fmt.Println("Detecting project type in:", projectPath)
 
 	for _, dir := range commonDirs {
 		fullPath := filepath.Join(projectPath, dir)
-		fmt.Println("Checking directory:", fullPath)
+		slog.Debug("checking directory", "path", fullPath)
 
 		// 1. Dynamic: package.json with "start" script
 		pkgPath := filepath.Join(fullPath, "package.json")
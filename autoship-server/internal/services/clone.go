@@ -2,32 +2,92 @@
 package services
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// In future add support for cloning specific branches or commits
+// CloneRoot returns the directory every deployment gets its own clone
+// directory in. It lies outside ./static, which the server serves publicly.
+func CloneRoot() string {
+	return filepath.Join(os.TempDir(), "autoship-clones")
+}
 
-// This only clones main branch of the repo
-func CloneRepository(repoURL, username, repoName string) (string, error) {
-	path := fmt.Sprintf("static/%s/%s", username, repoName)
+// GitCredentials authenticate a clone of a private repository over HTTPS.
+// They come from gitprovider.Provider.CloneCredentials and are never stored.
+type GitCredentials struct {
+	Username string
+	Password string
+}
 
-	if _, err := os.Stat(path); err == nil {
-		return path, nil // already cloned
+// ValidBranchName reports whether branch is a plausible branch name to hand
+// to git clone, following the main rules of git check-ref-format.
+func ValidBranchName(branch string) bool {
+	if branch == "" || len(branch) > 255 || strings.HasPrefix(branch, "-") || strings.HasPrefix(branch, "/") ||
+		strings.HasSuffix(branch, "/") || strings.HasSuffix(branch, ".lock") || strings.Contains(branch, "..") ||
+		strings.Contains(branch, "@{") {
+		return false
+	}
+	for _, r := range branch {
+		if r <= ' ' || r == 0x7f || strings.ContainsRune("~^:?*[\\", r) {
+			return false
+		}
 	}
+	return true
+}
+
+// CloneRepository clones branch of the repository, or its default branch when
+// branch is empty, and returns the path of the working tree,
+// <new dir under CloneRoot>/<repoName>. creds may be nil for public
+// repositories. Clones are never shared or reused, since one made with a
+// caller's credentials must not be handed to anyone else; callers remove it
+// with RemoveCloneDir when done.
+func CloneRepository(ctx context.Context, repoURL, repoName, branch string, creds *GitCredentials) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "git.clone", attribute.String("repo.url", repoURL))
+	defer func() { tracing.End(span, err) }()
+	log := logger.FromContext(ctx)
 
-	// Create new directory for the repository
-	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+	if err := os.MkdirAll(CloneRoot(), 0o700); err != nil {
 		return "", fmt.Errorf("failed to create dir: %v", err)
 	}
+	dir, err := os.MkdirTemp(CloneRoot(), "clone-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create dir: %v", err)
+	}
+	// The working tree keeps the repository's name, which the container
+	// name is derived from.
+	path := filepath.Join(dir, repoName)
 
 	// Execute the git clone command
-	cmd := exec.Command("git", "clone", repoURL, path)
+	args := []string{"clone"}
+	if branch != "" {
+		args = append(args, "--branch", branch, "--single-branch")
+	}
+	cmd := exec.Command("git", append(args, "--", repoURL, path)...)
+	// Fail instead of waiting for a password on private repositories.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if creds != nil {
+		// Passed as environment config rather than in the URL or argv so
+		// the secret doesn't show up in ps, .git/config or error messages.
+		basic := base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+basic,
+		)
+	}
 
-	fmt.Println("Cloning repository:", repoURL)
-	// Run the command and check for errors
-	fmt.Println("Executing command:", cmd.String())
+	log.Info("cloning repository", "repo_url", repoURL, "branch", branch, "path", path)
 	// cmd.Dir = "static" // Set working directory to static
 	// cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
 	// cmd.Env = append(cmd.Env, "GIT_ASKPASS=echo") // Disable password prompts
@@ -42,14 +102,21 @@ func CloneRepository(repoURL, username, repoName string) (string, error) {
 	// cmd.Env = append(cmd.Env, "GIT_CONFIG_GLOBAL_INIT_TEMPLATE_DIR=~/.git-templates") // Set global template directory
 	// cmd.Env = append(cmd.Env, "GIT_CONFIG_GLOBAL_INIT_COMMITTER_NAME="+username		) // Set global committer name
 
+	start := time.Now()
 	output, err := cmd.CombinedOutput()
-	fmt.Println("Git Output:\n", string(output))
-	fmt.Println("Git clone command finished.")
-	fmt.Println("Git Output:\n", string(output))
+	metrics.CloneDuration.Observe(time.Since(start).Seconds())
+	log.Debug("git clone finished", "output", string(output))
 
 	if err != nil {
+		_ = os.RemoveAll(dir)
 		return "", fmt.Errorf("git clone failed: %v\n%s", err, output)
 	}
-	fmt.Println("Repository cloned successfully to:", path)
+	log.Info("repository cloned", "path", path)
 	return path, nil
 }
+
+// RemoveCloneDir deletes a clone made by CloneRepository, given the path it
+// returned.
+func RemoveCloneDir(path string) error {
+	return os.RemoveAll(filepath.Dir(path))
+}
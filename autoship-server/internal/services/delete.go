@@ -3,12 +3,16 @@ package services
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
-// DeleteProject deletes a project's deployment by stopping and removing the Docker container.
+// DeleteProject deletes a project's deployment by stopping and removing the
+// Docker container. A container that no longer exists counts as removed.
 func DeleteProject(containerName string) error {
 	if containerName == "" {
 		return fmt.Errorf("container name required")
@@ -18,22 +22,34 @@ func DeleteProject(containerName string) error {
 	defer cancel()
 
 	// Stop the container (best-effort)
-	log.Printf("Stopping container %s", containerName)
+	slog.Info("stopping container", "container", containerName)
 	stopCmd := exec.CommandContext(ctx, "docker", "stop", containerName)
-	if out, err := stopCmd.CombinedOutput(); err != nil {
-		log.Printf("failed to stop container %s: %v, output: %s", containerName, err, string(out))
-	} else {
-		log.Printf("Successfully stopped container %s: %s", containerName, string(out))
+	if out, err := stopCmd.CombinedOutput(); err != nil && !strings.Contains(string(out), "No such container") {
+		slog.Warn("failed to stop container", "container", containerName, "error", err, "output", string(out))
 	}
 
 	// Remove the container (force + remove volumes)
-	log.Printf("Removing container %s", containerName)
+	slog.Info("removing container", "container", containerName)
 	rmCmd := exec.CommandContext(ctx, "docker", "rm", "-f", "-v", containerName)
-	if out, err := rmCmd.CombinedOutput(); err != nil {
+	if out, err := rmCmd.CombinedOutput(); err != nil && !strings.Contains(string(out), "No such container") {
 		return fmt.Errorf("failed to remove container %s: %v, output: %s", containerName, err, string(out))
-	} else {
-		log.Printf("Successfully removed container %s: %s", containerName, string(out))
 	}
 
 	return nil
 }
+
+// RemoveImage deletes the image built for containerName. A missing image
+// counts as removed.
+func RemoveImage(ctx context.Context, containerName string) error {
+	imageTag := strings.ToLower(strings.TrimSpace(containerName)) + ":latest"
+	out, err := exec.CommandContext(ctx, "docker", "rmi", "-f", imageTag).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "No such image") {
+		return fmt.Errorf("failed to remove image %s: %v, output: %s", imageTag, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RemoveBuildLogs deletes the captured build output of containerName.
+func RemoveBuildLogs(containerName string) error {
+	return os.RemoveAll(filepath.Dir(BuildLogPath(containerName)))
+}
@@ -0,0 +1,89 @@
+// internal/services/docker.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CheckContainerRuntime verifies the Docker daemon answers on the mounted
+// socket and returns its server version.
+func CheckContainerRuntime(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker daemon unreachable: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CountRunningContainers returns the number of running Auto-Ship app
+// containers (every deployed container is named "autoship-...").
+func CountRunningContainers(ctx context.Context) (int, error) {
+	out, err := exec.CommandContext(ctx, "docker", "ps", "-q", "--filter", "name=autoship-").Output()
+	if err != nil {
+		return 0, fmt.Errorf("docker ps failed: %w", err)
+	}
+	return len(strings.Fields(string(out))), nil
+}
+
+// ListContainerNames returns the names of every Auto-Ship app container,
+// running or stopped.
+func ListContainerNames(ctx context.Context) (map[string]bool, error) {
+	out, err := exec.CommandContext(ctx, "docker", "ps", "-a", "--format", "{{.Names}}", "--filter", "name=autoship-").Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker ps failed: %w", err)
+	}
+	names := map[string]bool{}
+	for _, name := range strings.Fields(string(out)) {
+		names[name] = true
+	}
+	return names, nil
+}
+
+// ContainerExists reports whether a container named containerName exists,
+// running or stopped.
+func ContainerExists(ctx context.Context, containerName string) (bool, error) {
+	out, err := exec.CommandContext(ctx, "docker", "container", "inspect", "--format", "{{.Name}}", containerName).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "No such container") {
+			return false, nil
+		}
+		return false, fmt.Errorf("docker container inspect failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return true, nil
+}
+
+// StopContainer stops a deployed container without removing it.
+func StopContainer(ctx context.Context, containerName string) error {
+	out, err := exec.CommandContext(ctx, "docker", "stop", containerName).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker stop failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// StartContainer starts a container stopped by StopContainer, keeping its
+// port mapping and memory limit.
+func StartContainer(ctx context.Context, containerName string) error {
+	out, err := exec.CommandContext(ctx, "docker", "start", containerName).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker start failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// UpdateContainerMemory changes a container's memory limit in place. Swap is
+// kept at docker run's default of twice the memory.
+func UpdateContainerMemory(ctx context.Context, containerName string, memoryMB int) error {
+	out, err := exec.CommandContext(ctx, "docker", "update",
+		"--memory", fmt.Sprintf("%dm", memoryMB),
+		"--memory-swap", fmt.Sprintf("%dm", 2*memoryMB),
+		containerName,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker update failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -1,16 +1,24 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	// "io/ioutil"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/logger"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/metrics"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/ports"
+	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/tracing"
 	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/utils"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Environment represents the type of backend environment detected.
@@ -23,8 +31,8 @@ const (
 	EnvUnknown Environment = "unknown"
 )
 
-// detectEnvironment inspects the repo to determine the runtime environment.
-func detectEnvironment(repoPath string) Environment {
+// DetectEnvironment inspects the repo to determine the runtime environment.
+func DetectEnvironment(repoPath string) Environment {
 	var foundNode, foundPython, foundGo bool
 
 	err := filepath.WalkDir(repoPath, func(path string, d os.DirEntry, err error) error {
@@ -32,7 +40,7 @@ func detectEnvironment(repoPath string) Environment {
 			return nil // ignore errors
 		}
 
-		fmt.Printf("Visited: %s\n", path)
+		slog.Debug("visited", "path", path)
 		// Normalize file name (in case-insensitive FS)
 		switch strings.ToLower(d.Name()) {
 		case "package.json":
@@ -52,19 +60,16 @@ func detectEnvironment(repoPath string) Environment {
 	})
 
 	if err != nil {
-		fmt.Println("error walking repo:", err)
+		slog.Warn("error walking repo", "path", repoPath, "error", err)
 		return EnvUnknown
 	}
 
 	switch {
 	case foundNode:
-		fmt.Println("Detected Node.js environment")
 		return EnvNode
 	case foundPython:
-		fmt.Println("Detected Python environment")
 		return EnvPython
 	case foundGo:
-		fmt.Println("Detected Go environment")
 		return EnvGo
 	default:
 		return EnvUnknown
@@ -77,7 +82,6 @@ func GenerateDockerfile(env Environment, repoPath, startCommand string) error {
 	var baseImage string
 	var installCmd string
 	var netToolsInstall string
-	fmt.Println("repopath:", repoPath)
 	switch env {
 	case EnvNode:
 		baseImage = "node:18"
@@ -122,7 +126,9 @@ func GenerateDockerfile(env Environment, repoPath, startCommand string) error {
 }
 
 // buildAndRunContainer builds the Docker image and runs it on a specified port.
-func buildAndRunContainerHybrid(repoPath, containerName string) (int, int, error) {
+// memoryMB caps the final container's memory (docker --memory).
+func buildAndRunContainerHybrid(ctx context.Context, env Environment, repoPath, containerName string, memoryMB int) (int, int, error) {
+	log := logger.FromContext(ctx)
 	// Derive image tag from container name
 	if containerName == "" {
 		return 0, 0, fmt.Errorf("container name cannot be empty")
@@ -135,19 +141,33 @@ func buildAndRunContainerHybrid(repoPath, containerName string) (int, int, error
 	// Derive image tag from container name
 	imageTag := containerName + ":latest"
 
+	// Capture the build output per deployment so it can be served later via
+	// GET /projects/:id/logs?type=build, while still echoing it to stdout.
+	buildLog, err := createBuildLog(containerName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create build log: %w", err)
+	}
+	defer buildLog.Close()
+	logOut := io.MultiWriter(os.Stdout, buildLog)
+
 	// Step 1: Build image
+	log.Info("building image", "image", imageTag)
 	buildCmd := exec.Command("docker", "build", "-t", imageTag, ".")
 	buildCmd.Dir = repoPath
-	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
-	if err := buildCmd.Run(); err != nil {
+	buildCmd.Stdout = logOut
+	buildCmd.Stderr = logOut
+	_, buildSpan := tracing.Start(ctx, "docker.build", attribute.String("container.image.name", imageTag))
+	buildStart := time.Now()
+	err = buildCmd.Run()
+	metrics.BuildDuration.WithLabelValues(string(env)).Observe(time.Since(buildStart).Seconds())
+	tracing.End(buildSpan, err)
+	if err != nil {
 		return 0, 0, fmt.Errorf("docker build failed: %w", err)
 	}
 
 	// Step 2: Run temp container
-	fmt.Println("Building a temporary Container 88 88888888 888888888888 88888888888 888888888888   888888888888 888888888888 8888888     888888")
 	tmpContainer := containerName + "-tmp"
-	fmt.Println("Running temporary container for port detection... ", tmpContainer)
+	log.Info("running temporary container for port detection", "container", tmpContainer)
 	// Create a temporary container to detect exposed port
 	// Use the same image tag but with a different name
 	// This avoids conflicts with the final container
@@ -158,9 +178,7 @@ func buildAndRunContainerHybrid(repoPath, containerName string) (int, int, error
 	// }
 
 	// Run the temporary container in detached mode
-	// Use the image tag built earlier
-	fmt.Println("Running temporary container for port detection... ", tmpContainer)
-
+	_, detectSpan := tracing.Start(ctx, "docker.detect_port", attribute.String("container.name", tmpContainer))
 	// Use the image tag built earlier
 	// Use a simple command that keeps the container running
 	// This is just to keep the container alive for port detection
@@ -171,14 +189,14 @@ func buildAndRunContainerHybrid(repoPath, containerName string) (int, int, error
 	// Note: This is a temporary container, it will be removed after port detection
 	// Use the image tag built earlier
 	runCmd := exec.Command("docker", "run", "-d", "--name", tmpContainer, imageTag)
-	runCmd.Stdout = os.Stdout
-	runCmd.Stderr = os.Stderr
+	runCmd.Stdout = logOut
+	runCmd.Stderr = logOut
 	if err := runCmd.Run(); err != nil {
+		tracing.End(detectSpan, err)
 		return 0, 0, fmt.Errorf("docker run (tmp) failed: %w", err)
 	}
 
 	// Step 3: Wait for the container to be fully up
-	fmt.Println("Waiting for the temporary container to be fully up... ", tmpContainer)
 	// Sleep for few seconds to ensure the container is fully up
 	// This is important to ensure the container is ready to accept connections
 	// You can adjust the sleep duration based on your needs
@@ -186,19 +204,19 @@ func buildAndRunContainerHybrid(repoPath, containerName string) (int, int, error
 	time.Sleep(5 * time.Second) // <-- wait for the container to be fully up
 
 	// Step 4: Detect exposed port
-	fmt.Println("Detecting Exposed Port PPPPPPPPPPPPPPPPPPPPPPPPPPOOOOOOOOOOOOOOOOOOOOOOOOORRRRRRRRRRRRRRRRRRRRRRTTTTTTTTTTTTTTTTTTTTTTTTTTTT")
 	containerPort, err := utils.DetectExposedPort(tmpContainer)
+	tracing.End(detectSpan, err)
 	if err != nil {
 		logsCmd := exec.Command("docker", "logs", tmpContainer)
 		logsOutput, _ := logsCmd.CombinedOutput()
-		fmt.Println("Temporary container logs:\n", string(logsOutput))
+		fmt.Fprintln(logOut, "Temporary container logs:\n", string(logsOutput))
 
 		_ = exec.Command("docker", "rm", "-f", tmpContainer).Run()
 		return 0, 0, fmt.Errorf("port detection failed: %w", err)
 	}
+	log.Info("detected container port", "container_port", containerPort)
 
 	// // Step 5: Pick host port
-	// fmt.Println("Detected container port in isPortAvailable function:", containerPort)
 	// hostPort := containerPort
 	// if !utils.IsPortAvailable(hostPort) {
 	// 	hostPort, err = utils.FindFreeHostPort()
@@ -212,38 +230,51 @@ func buildAndRunContainerHybrid(repoPath, containerName string) (int, int, error
 	// 	}
 	// }
 
-	fmt.Println("Finding free host port using MongoDB ...")
-	hostPort, err := utils.GetOrReserveValidFreePort(containerName)
+	reserveCtx, reserveSpan := tracing.Start(ctx, "ports.reserve")
+	hostPort, err := ports.Lease(reserveCtx, containerName)
+	tracing.End(reserveSpan, err)
 	if err != nil {
 		logsCmd := exec.Command("docker", "logs", tmpContainer)
 		logsOutput, _ := logsCmd.CombinedOutput()
-		fmt.Println("Temporary container logs:\n", string(logsOutput))
+		fmt.Fprintln(logOut, "Temporary container logs:\n", string(logsOutput))
 
 		_ = exec.Command("docker", "rm", "-f", tmpContainer).Run()
 		return 0, 0, fmt.Errorf("failed to find free host port: %w", err)
 	}
 
-	fmt.Println("Authorizing host port via cloud firewall: ", hostPort)
-	if err := cloud.Get().AuthorizePort(hostPort); err != nil {
-		_ = exec.Command("docker", "rm", "-f", tmpContainer).Run()
-		return 0, 0, fmt.Errorf("firewall authorize error: %w", err)
+	// In private network mode only the host's proxy reaches the port.
+	if !PrivateNetwork() {
+		log.Info("authorizing host port via cloud firewall", "host_port", hostPort)
+		if err := cloud.Get().AuthorizePort(ctx, hostPort); err != nil {
+			_ = exec.Command("docker", "rm", "-f", tmpContainer).Run()
+			_ = ports.Release(ctx, hostPort, containerName)
+			return 0, 0, fmt.Errorf("firewall authorize error: %w", err)
+		}
 	}
 
 	// Optional: Commit container state (e.g., installed files)
 	_ = exec.Command("docker", "commit", tmpContainer, imageTag).Run()
 	_ = exec.Command("docker", "rm", "-f", tmpContainer).Run()
 
-	fmt.Println("Making                                       final                         Container")
 	// Step 6: Run final container
-	finalCmd := exec.Command(
-		"docker", "run", "-d",
-		"-p", fmt.Sprintf("%d:%d", hostPort, containerPort),
+	log.Info("starting final container", "container", containerName, "host_port", hostPort, "container_port", containerPort)
+	runArgs := append([]string{"run", "-d"}, publishArgs(hostPort, containerPort)...)
+	finalCmd := exec.Command("docker", append(runArgs,
+		"--memory", fmt.Sprintf("%dm", memoryMB),
 		"--name", containerName,
 		imageTag,
-	)
-	finalCmd.Stdout = os.Stdout
-	finalCmd.Stderr = os.Stderr
-	if err := finalCmd.Run(); err != nil {
+	)...)
+	finalCmd.Stdout = logOut
+	finalCmd.Stderr = logOut
+	_, runSpan := tracing.Start(ctx, "docker.run", attribute.String("container.name", containerName))
+	err = finalCmd.Run()
+	tracing.End(runSpan, err)
+	if err != nil {
+		_ = exec.Command("docker", "rm", "-f", containerName).Run()
+		if !PrivateNetwork() {
+			_ = cloud.Get().RevokePort(ctx, hostPort)
+		}
+		_ = ports.Release(ctx, hostPort, containerName)
 		return 0, 0, fmt.Errorf("docker final run failed: %w", err)
 	}
 
@@ -251,7 +282,10 @@ func buildAndRunContainerHybrid(repoPath, containerName string) (int, int, error
 }
 
 // FullPipeline executes the full flow: detects env, generates Dockerfile, builds, and runs container.
-func FullPipeline(username, repoPath, envContent, startCommand string) (int, int, string, error) {
+func FullPipeline(ctx context.Context, username, repoPath, envContent, startCommand string, memoryMB int) (containerPort, hostPort int, containerName string, err error) {
+	ctx, span := tracing.Start(ctx, "pipeline.dynamic", attribute.String("repo.path", repoPath))
+	defer func() { tracing.End(span, err) }()
+
 	// Step 1: Save .env if provided
 	if envContent != "" {
 		if err := utils.SaveEnvFile(repoPath, envContent); err != nil {
@@ -260,10 +294,12 @@ func FullPipeline(username, repoPath, envContent, startCommand string) (int, int
 	}
 
 	// Step 2: Detect environment
-	envType := detectEnvironment(repoPath)
+	envType := DetectEnvironment(repoPath)
 	if envType == EnvUnknown {
 		return 0, 0, "", fmt.Errorf("unsupported environment")
 	}
+	logger.FromContext(ctx).Info("detected environment", "environment", envType)
+	span.SetAttributes(attribute.String("autoship.environment", string(envType)))
 
 	// Step 3: Generate Dockerfile dynamically
 	if err := GenerateDockerfile(envType, repoPath, startCommand); err != nil {
@@ -274,14 +310,16 @@ func FullPipeline(username, repoPath, envContent, startCommand string) (int, int
 	repoName := filepath.Base(repoPath)
 	// containerName := fmt.Sprintf("autoship-%s-%s", username, strings.ToLower(repoName))
 	timestamp := time.Now().Unix()
-	containerName := fmt.Sprintf("autoship-%s-%s-%d", username, strings.ToLower(repoName), timestamp)
+	// Lowercased like the image tag and the container buildAndRunContainerHybrid
+	// actually runs, so the name stored on the project matches it.
+	containerName = strings.ToLower(fmt.Sprintf("autoship-%s-%s-%d", username, repoName, timestamp))
 
 	// Step 5: Build and run container
-	containerPort, hostPort, err := buildAndRunContainerHybrid(repoPath, containerName)
+	containerPort, hostPort, err = buildAndRunContainerHybrid(ctx, envType, repoPath, containerName, memoryMB)
 	if err != nil {
 		return 0, 0, "", fmt.Errorf("container error: %w", err)
 	}
 
-	log.Printf("Container %s started: hostPort=%d, containerPort=%d", containerName, hostPort, containerPort)
+	logger.FromContext(ctx).Info("container started", "container", containerName, "host_port", hostPort, "container_port", containerPort)
 	return containerPort, hostPort, containerName, nil
 }
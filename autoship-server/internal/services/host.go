@@ -31,3 +31,19 @@ func DetectHostProjectType(path string) string {
 	}
 	return "unknown"
 }
+
+// StaticSiteSize returns the total size of the regular files under path, which
+// is what UploadStaticSite will store.
+func StaticSiteSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
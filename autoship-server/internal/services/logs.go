@@ -0,0 +1,103 @@
+// internal/services/logs.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// defaultLogDir is where per-deployment build logs are kept when
+// AUTOSHIP_LOG_DIR is not set. It sits next to the deploy-agent queue files.
+const defaultLogDir = "/var/lib/autoship/logs"
+
+// LogOptions filters the output of BuildLogs and ContainerLogs.
+type LogOptions struct {
+	Follow bool   // keep streaming new lines (runtime logs only)
+	Tail   int    // only the last N lines; 0 means everything
+	Since  string // docker --since value, e.g. "10m" or an RFC3339 timestamp (runtime logs only)
+}
+
+// LogDir returns the root directory for captured build logs.
+func LogDir() string {
+	if dir := os.Getenv("AUTOSHIP_LOG_DIR"); dir != "" {
+		return dir
+	}
+	return defaultLogDir
+}
+
+// BuildLogPath returns the file that holds the build output for containerName.
+func BuildLogPath(containerName string) string {
+	return filepath.Join(LogDir(), containerName, "build.log")
+}
+
+// createBuildLog truncates (or creates) the build log for containerName.
+func createBuildLog(containerName string) (*os.File, error) {
+	path := BuildLogPath(containerName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log dir: %w", err)
+	}
+	return os.Create(path)
+}
+
+// BuildLogs returns the captured build output for containerName, trimmed to
+// the last opts.Tail lines when set. Build logs are written once per deploy,
+// so Follow and Since are ignored.
+func BuildLogs(containerName string, opts LogOptions) (io.ReadCloser, error) {
+	data, err := os.ReadFile(BuildLogPath(containerName))
+	if err != nil {
+		return nil, fmt.Errorf("build log not available: %w", err)
+	}
+	if opts.Tail > 0 {
+		data = tailLines(data, opts.Tail)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// ContainerLogs runs `docker logs` for containerName and returns a reader over
+// its combined stdout/stderr. With opts.Follow the reader stays open until ctx
+// is cancelled or the container stops; callers must Close it either way.
+func ContainerLogs(ctx context.Context, containerName string, opts LogOptions) (io.ReadCloser, error) {
+	if containerName == "" {
+		return nil, fmt.Errorf("container name required")
+	}
+
+	args := []string{"logs"}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Tail > 0 {
+		args = append(args, fmt.Sprintf("--tail=%d", opts.Tail))
+	}
+	if opts.Since != "" {
+		args = append(args, "--since="+opts.Since)
+	}
+	args = append(args, containerName)
+
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		pw.Close()
+		return nil, fmt.Errorf("failed to start docker logs: %w", err)
+	}
+	go func() {
+		pw.CloseWithError(cmd.Wait())
+	}()
+	return pr, nil
+}
+
+// tailLines returns the last n lines of data.
+func tailLines(data []byte, n int) []byte {
+	lines := strings.SplitAfter(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) <= n {
+		return data
+	}
+	return []byte(strings.Join(lines[len(lines)-n:], "") + "\n")
+}
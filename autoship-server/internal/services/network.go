@@ -0,0 +1,87 @@
+// internal/services/network.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Container network modes, chosen with CONTAINER_NETWORK_MODE.
+const (
+	// NetworkPublic publishes every app on a host port on all interfaces
+	// and opens it in the cloud firewall (the original behavior).
+	NetworkPublic = "public"
+	// NetworkPrivate attaches apps to a dedicated Docker network and
+	// publishes their port on CONTAINER_BIND_ADDRESS only, where the host's
+	// nginx reaches them. Nothing is opened in the cloud firewall.
+	NetworkPrivate = "private"
+)
+
+var network = struct {
+	mode        string
+	name        string
+	bindAddress string
+}{mode: NetworkPublic, name: "autoship-apps", bindAddress: "127.0.0.1"}
+
+// InitNetwork reads CONTAINER_NETWORK_MODE (public or private),
+// CONTAINER_NETWORK (default autoship-apps) and CONTAINER_BIND_ADDRESS
+// (default 127.0.0.1, or e.g. the network's bridge gateway). In private mode
+// it creates the Docker network if it does not exist yet. It must be called
+// once at startup.
+func InitNetwork(ctx context.Context) error {
+	if v := os.Getenv("CONTAINER_NETWORK_MODE"); v != "" {
+		if v != NetworkPublic && v != NetworkPrivate {
+			return fmt.Errorf("unknown CONTAINER_NETWORK_MODE %q (expected %q or %q)", v, NetworkPublic, NetworkPrivate)
+		}
+		network.mode = v
+	}
+	if v := os.Getenv("CONTAINER_NETWORK"); v != "" {
+		network.name = v
+	}
+	if v := os.Getenv("CONTAINER_BIND_ADDRESS"); v != "" {
+		if net.ParseIP(v) == nil {
+			return fmt.Errorf("invalid CONTAINER_BIND_ADDRESS %q", v)
+		}
+		network.bindAddress = v
+	}
+	if network.mode != NetworkPrivate {
+		return nil
+	}
+
+	if exec.CommandContext(ctx, "docker", "network", "inspect", network.name).Run() == nil {
+		return nil
+	}
+	out, err := exec.CommandContext(ctx, "docker", "network", "create", "--driver", "bridge", network.name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create docker network %s: %v: %s", network.name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// PrivateNetwork reports whether apps run in private network mode, where
+// host ports are not opened in the cloud firewall.
+func PrivateNetwork() bool { return network.mode == NetworkPrivate }
+
+// UpstreamHost returns the address the host's proxy reaches app ports on.
+func UpstreamHost() string {
+	if PrivateNetwork() {
+		return network.bindAddress
+	}
+	return "localhost"
+}
+
+// publishArgs returns the docker run flags that attach the container and
+// publish containerPort on hostPort.
+func publishArgs(hostPort, containerPort int) []string {
+	if PrivateNetwork() {
+		return []string{
+			"--network", network.name,
+			"-p", fmt.Sprintf("%s:%d:%d", network.bindAddress, hostPort, containerPort),
+		}
+	}
+	return []string{"-p", fmt.Sprintf("%d:%d", hostPort, containerPort)}
+}
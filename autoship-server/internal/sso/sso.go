@@ -0,0 +1,199 @@
+// Package sso implements single sign-on against a generic OpenID Connect
+// identity provider (Keycloak, Okta, Azure AD, ...): discovery, the
+// authorization code flow with PKCE, ID token verification and an optional
+// email-domain allowlist.
+//
+// SSO is off unless OIDC_ISSUER_URL and OIDC_CLIENT_ID are set. Discovery runs
+// on first use, so an IdP that is briefly down at startup does not keep the
+// server from booting.
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ProviderName is the identity key under which SSO accounts are linked
+// (models.User.Identities) and the :provider segment of its routes.
+const ProviderName = "sso"
+
+// ErrDomainNotAllowed is returned for users whose email domain is not in
+// OIDC_ALLOWED_DOMAINS.
+var ErrDomainNotAllowed = errors.New("email domain is not allowed to sign in")
+
+// Identity is the verified subject of an ID token.
+type Identity struct {
+	Subject string
+	Login   string // preferred_username, informational only
+	Email   string // lowercased; only set when the IdP vouches for it
+}
+
+type config struct {
+	issuer, clientID, clientSecret, redirectURI string
+	scopes                                      []string
+	allowedDomains                              []string
+	trustUnverifiedEmail                        bool
+}
+
+var (
+	cfg *config
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+)
+
+// Init reads the OIDC_* environment variables. It must be called once at
+// startup.
+func Init() error {
+	issuer := strings.TrimRight(os.Getenv("OIDC_ISSUER_URL"), "/")
+	clientID := os.Getenv("OIDC_CLIENT_ID")
+	if issuer == "" || clientID == "" {
+		cfg = nil
+		return nil
+	}
+	redirectURI := os.Getenv("OIDC_REDIRECT_URI")
+	if redirectURI == "" {
+		return fmt.Errorf("OIDC_REDIRECT_URI must be set when OIDC_ISSUER_URL is")
+	}
+
+	scopes := []string{oidc.ScopeOpenID, "email", "profile"}
+	if v := os.Getenv("OIDC_SCOPES"); v != "" {
+		scopes = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	}
+
+	var domains []string
+	for _, d := range strings.Split(os.Getenv("OIDC_ALLOWED_DOMAINS"), ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			domains = append(domains, strings.TrimPrefix(d, "@"))
+		}
+	}
+
+	cfg = &config{
+		issuer:         issuer,
+		clientID:       clientID,
+		clientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+		redirectURI:    redirectURI,
+		scopes:         scopes,
+		allowedDomains: domains,
+		// Azure AD omits email_verified; its tenant admins control emails.
+		trustUnverifiedEmail: os.Getenv("OIDC_TRUST_UNVERIFIED_EMAIL") == "true",
+	}
+
+	// Metadata discovered for a previous configuration is stale.
+	mu.Lock()
+	provider = nil
+	mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether SSO is configured.
+func Enabled() bool { return cfg != nil }
+
+// discover returns the provider metadata, fetching it on first success.
+func discover(ctx context.Context) (*oidc.Provider, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider != nil {
+		return provider, nil
+	}
+	p, err := oidc.NewProvider(ctx, cfg.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery for %s failed: %w", cfg.issuer, err)
+	}
+	provider = p
+	return p, nil
+}
+
+func oauth2Config(p *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     cfg.clientID,
+		ClientSecret: cfg.clientSecret,
+		RedirectURL:  cfg.redirectURI,
+		Endpoint:     p.Endpoint(),
+		Scopes:       cfg.scopes,
+	}
+}
+
+// AuthURL returns the IdP's authorization URL for state, the ID token nonce
+// and the S256 PKCE challenge.
+func AuthURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	p, err := discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth2Config(p).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nil
+}
+
+// Exchange redeems code, verifies the returned ID token (signature, issuer,
+// audience, expiry and nonce) and returns its subject. The email is checked
+// against OIDC_ALLOWED_DOMAINS.
+func Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	p, err := discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth2Config(p).Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("OIDC code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("OIDC token response has no id_token")
+	}
+
+	idToken, err := p.Verifier(&oidc.Config{ClientID: cfg.clientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     *bool  `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode ID token claims: %w", err)
+	}
+
+	identity := &Identity{Subject: idToken.Subject, Login: claims.PreferredUsername}
+	verified := claims.EmailVerified != nil && *claims.EmailVerified
+	if claims.Email != "" && (verified || cfg.trustUnverifiedEmail) {
+		identity.Email = strings.ToLower(claims.Email)
+	}
+
+	if len(cfg.allowedDomains) > 0 && !domainAllowed(identity.Email) {
+		return nil, ErrDomainNotAllowed
+	}
+	return identity, nil
+}
+
+// domainAllowed reports whether email belongs to one of the allowed domains
+// (exact match, not subdomains).
+func domainAllowed(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, d := range cfg.allowedDomains {
+		if domain == d {
+			return true
+		}
+	}
+	return false
+}
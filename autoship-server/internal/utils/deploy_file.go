@@ -9,6 +9,15 @@ import (
 	"time"
 )
 
+// Files shared with the deploy agent (autoship-scripts) on the host.
+const (
+	DeployDir           = "/var/lib/autoship/deploy"
+	DeployRequestsFile  = DeployDir + "/deploy-requests.json"
+	DeployResponsesFile = DeployDir + "/deploy-responses.json"
+	// AgentHeartbeatFile is rewritten by the agent every few seconds.
+	AgentHeartbeatFile = DeployDir + "/agent-heartbeat"
+)
+
 // Mutex to prevent race conditions when appending to file
 var fileMutex sync.Mutex
 
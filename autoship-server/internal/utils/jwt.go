@@ -2,6 +2,7 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -10,8 +11,22 @@ import (
 	"github.com/joho/godotenv"
 )
 
-var jwtKey []byte
 var jwtExpiration time.Duration
+var refreshExpiration time.Duration
+var jwtIssuer, jwtAudience string
+
+// defaultRefreshExpiration is used when REFRESH_TOKEN_EXPIRATION is unset.
+const defaultRefreshExpiration = 30 * 24 * time.Hour
+
+// Defaults for the iss/aud claims, overridable with JWT_ISSUER / JWT_AUDIENCE.
+const (
+	defaultJWTIssuer   = "autoship-server"
+	defaultJWTAudience = "autoship-api"
+)
+
+// validSigningMethods are the only algorithms VerifyJWT accepts. Pinning them
+// rules out "none" and HS256-with-the-public-key confusion.
+var validSigningMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
 
 // Claims struct for JWT
 type Claims struct {
@@ -20,14 +35,21 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// LoadEnv loads environment variables from the .env file
+// LoadEnv loads environment variables from the .env file, if there is one,
+// and configures token signing from them.
 func LoadEnv() error {
-	if err := godotenv.Load(); err != nil {
-		return fmt.Errorf("error loading .env file: %w", err)
+	// Without a .env file the variables come from the environment.
+	_ = godotenv.Load()
+
+	// Load signing keys and expiration time from environment variables
+	keys, err := loadKeySet()
+	if err != nil {
+		return err
 	}
+	jwtKeys = keys
 
-	// Set JWT secret key and expiration time from environment variables
-	jwtKey = []byte(os.Getenv("JWT_SECRET"))
+	jwtIssuer = envOr("JWT_ISSUER", defaultJWTIssuer)
+	jwtAudience = envOr("JWT_AUDIENCE", defaultJWTAudience)
 
 	expiration, err := time.ParseDuration(os.Getenv("JWT_EXPIRATION"))
 	if err != nil {
@@ -35,9 +57,29 @@ func LoadEnv() error {
 	}
 	jwtExpiration = expiration
 
+	refreshExpiration = defaultRefreshExpiration
+	if v := os.Getenv("REFRESH_TOKEN_EXPIRATION"); v != "" {
+		refreshExpiration, err = time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("error parsing REFRESH_TOKEN_EXPIRATION: %w", err)
+		}
+	}
+
 	return nil
 }
 
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// RefreshTokenExpiration returns how long an issued refresh token stays valid.
+func RefreshTokenExpiration() time.Duration {
+	return refreshExpiration
+}
+
 // GenerateJWT generates a new JWT token
 func GenerateJWT(userID, email string) (string, error) {
 	expirationTime := time.Now().Add(jwtExpiration)
@@ -46,33 +88,78 @@ func GenerateJWT(userID, email string) (string, error) {
 		Email:  email,
 
 		RegisteredClaims: jwt.RegisteredClaims{
+			// The jti lets a single access token be revoked on logout.
+			ID:        GenerateRandomID(),
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{jwtAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	return signClaims(claims)
+}
+
+// VerifyJWT verifies a token and returns the claims. The token must name a
+// known key in its kid header, be signed with that key's algorithm, and carry
+// our issuer and audience.
+func VerifyJWT(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	if err := parseClaims(tokenStr, claims, jwtAudience); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// auditedClaims is satisfied by any claims type embedding jwt.RegisteredClaims.
+type auditedClaims interface {
+	jwt.Claims
+	VerifyIssuer(cmp string, req bool) bool
+	VerifyAudience(cmp string, req bool) bool
+}
+
+// signClaims signs claims with the active key and names it in the kid header.
+func signClaims(claims jwt.Claims) (string, error) {
+	key := jwtKeys.active
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	tokenString, err := token.SignedString(key.private)
 	if err != nil {
 		return "", fmt.Errorf("error signing token: %w", err)
 	}
-
 	return tokenString, nil
 }
 
-// VerifyJWT verifies a token and returns the claims
-func VerifyJWT(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
+// parseClaims verifies tokenStr into claims. Besides the signature it checks
+// our issuer and the given audience, which keeps short-lived tokens minted for
+// other purposes (OAuth state, link tickets) from passing as access tokens.
+func parseClaims(tokenStr string, claims auditedClaims, audience string) error {
+	parser := jwt.NewParser(jwt.WithValidMethods(validSigningMethods))
+	token, err := parser.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := jwtKeys.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.public, nil
 	})
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, err
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	if !claims.VerifyIssuer(jwtIssuer, true) {
+		return errors.New("invalid token issuer")
+	}
+	if !claims.VerifyAudience(audience, true) {
+		return errors.New("invalid token audience")
 	}
 
-	return claims, nil
+	return nil
 }
@@ -0,0 +1,46 @@
+// internal/utils/mfa_challenge.go
+package utils
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mfaChallengeAudience keeps challenge tokens from passing as access tokens.
+const mfaChallengeAudience = "autoship-mfa-challenge"
+
+// MFAChallengeTTL is how long a user has to enter their second factor after
+// the first one was accepted.
+const MFAChallengeTTL = 5 * time.Minute
+
+// mfaChallenge proves that UserID passed the first login factor.
+type mfaChallenge struct {
+	UserID string `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+// GenerateMFAChallenge returns the token a login hands out in place of a
+// session when the user has two-factor authentication enabled.
+func GenerateMFAChallenge(userID string) (string, error) {
+	now := time.Now()
+	return signClaims(&mfaChallenge{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        GenerateRandomID(),
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFAChallengeTTL)),
+		},
+	})
+}
+
+// VerifyMFAChallenge returns the user a challenge token was issued to.
+func VerifyMFAChallenge(token string) (string, error) {
+	ch := &mfaChallenge{}
+	if err := parseClaims(token, ch, mfaChallengeAudience); err != nil {
+		return "", err
+	}
+	return ch.UserID, nil
+}
@@ -0,0 +1,120 @@
+// internal/utils/oauth_state.go
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Audiences of the short-lived tokens used during OAuth logins. Each gets its
+// own so none of them can stand in for another or for an access token.
+const (
+	oauthFlowAudience  = "autoship-oauth-flow"
+	linkTicketAudience = "autoship-link-ticket"
+)
+
+// OAuthFlowTTL bounds how long a user may take on the provider's consent page.
+const OAuthFlowTTL = 10 * time.Minute
+
+// OAuthFlow is the per-login state of an OAuth/OIDC authorization code flow.
+// It travels signed in an HttpOnly cookie, never in a URL: State is sent to
+// the provider and must come back unchanged (login CSRF protection), Verifier
+// is the PKCE secret, and LinkUserID is set when an already signed-in user is
+// linking this identity to their account.
+type OAuthFlow struct {
+	State      string `json:"state"`
+	Verifier   string `json:"verifier"`
+	Nonce      string `json:"nonce,omitempty"`
+	LinkUserID string `json:"link_uid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewOAuthFlow creates the state for a new authorization request.
+func NewOAuthFlow(linkUserID string) (*OAuthFlow, error) {
+	state, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &OAuthFlow{
+		State:      state,
+		Verifier:   verifier,
+		Nonce:      nonce,
+		LinkUserID: linkUserID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{oauthFlowAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(OAuthFlowTTL)),
+		},
+	}, nil
+}
+
+// Sign returns the cookie value for f.
+func (f *OAuthFlow) Sign() (string, error) {
+	return signClaims(f)
+}
+
+// PKCEChallenge is the S256 code_challenge for the flow's verifier.
+func (f *OAuthFlow) PKCEChallenge() string {
+	sum := sha256.Sum256([]byte(f.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyOAuthFlow checks the signed cookie value and that the state returned
+// by the provider matches it.
+func VerifyOAuthFlow(cookie, state string) (*OAuthFlow, error) {
+	f := &OAuthFlow{}
+	if err := parseClaims(cookie, f, oauthFlowAudience); err != nil {
+		return nil, err
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(f.State), []byte(state)) != 1 {
+		return nil, errors.New("oauth state mismatch")
+	}
+	return f, nil
+}
+
+// linkTicket authorizes one account-linking flow for UserID.
+type linkTicket struct {
+	UserID string `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+// GenerateLinkTicket returns a short-lived token that lets the browser start
+// an OAuth flow linking a new identity to userID. It is handed out by an
+// authenticated API call because a plain browser redirect cannot carry the
+// Authorization header.
+func GenerateLinkTicket(userID string) (string, error) {
+	now := time.Now()
+	return signClaims(&linkTicket{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{linkTicketAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(2 * time.Minute)),
+		},
+	})
+}
+
+// VerifyLinkTicket returns the user a link ticket was issued to.
+func VerifyLinkTicket(ticket string) (string, error) {
+	t := &linkTicket{}
+	if err := parseClaims(ticket, t, linkTicketAudience); err != nil {
+		return "", err
+	}
+	return t.UserID, nil
+}
@@ -0,0 +1,71 @@
+// internal/utils/password.go
+package utils
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MinPasswordLength follows NIST SP 800-63B guidance for user-chosen
+	// secrets, with some headroom.
+	MinPasswordLength = 10
+	// MaxPasswordBytes is bcrypt's input limit; longer passwords would be
+	// silently truncated.
+	MaxPasswordBytes = 72
+)
+
+// commonPasswords rejects the passwords that top every breach list and still
+// pass the length and character-class rules.
+var commonPasswords = map[string]bool{
+	"1q2w3e4r5t": true, "1qaz2wsx3edc": true, "qwerty12345": true,
+	"password12": true, "password123": true, "password1234": true, "iloveyou123": true,
+	"letmein123": true, "welcome123": true, "changeme123": true, "passw0rd123": true,
+	"football123": true,
+}
+
+// ValidatePassword checks a new password against the strength rules: 10 to
+// 72 bytes, at least two character classes (letters, digits, symbols), not a
+// well-known password and not the user's email or its local part.
+func ValidatePassword(password, email string) error {
+	if len(password) < MinPasswordLength {
+		return errors.New("password must be at least 10 characters")
+	}
+	if len(password) > MaxPasswordBytes {
+		return errors.New("password must be at most 72 bytes")
+	}
+
+	var letter, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{letter, digit, other} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < 2 {
+		return errors.New("password must mix at least two of letters, digits and symbols")
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("password is too common")
+	}
+	if email = strings.ToLower(email); email != "" {
+		local, _, _ := strings.Cut(email, "@")
+		if lower == email || (len(local) >= 4 && strings.Contains(lower, local)) {
+			return errors.New("password must not contain your email address")
+		}
+	}
+	return nil
+}
@@ -1,140 +1,16 @@
 package utils
 
 import (
-	"context"
 	"fmt"
-	"github.com/Ashmit-Kumar/Auto-Ship/autoship-server/internal/cloud"
-	"github.com/joho/godotenv"
-	"log"
+	"log/slog"
 	"net"
-	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"time"
-
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-)
-
-var (
-	MongoURI       string
-	DatabaseName   string
-	CollectionName string
 )
 
-func init() {
-	_ = godotenv.Load(".env") // Loads .env file from current directory (ignore error if not present)
-
-	MongoURI = os.Getenv("MONGO_URI")
-	DatabaseName = os.Getenv("MONGO_DB_NAME")
-	CollectionName = os.Getenv("MONGO_DB_COLLECTION")
-}
-
-// // GetOrReserveValidFreePort finds an unused port and opens it in the EC2 security group
-// func GetOrReserveValidFreePort(containerName string) (int, error) {
-// 	ctx := context.Background()
-
-// 	// 1. Connect to MongoDB
-// 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoURI))
-// 	if err != nil {
-// 		return 0, err
-// 	}
-// 	defer client.Disconnect(ctx)
-
-// 	coll := client.Database(DatabaseName).Collection(CollectionName)
-
-// 	// 2. Search for an available port in DB
-// 	var portDoc struct {
-// 		Port int `bson:"port"`
-// 	}
-// 	err = coll.FindOneAndUpdate(ctx, bson.M{"status": "available"}, bson.M{
-// 		"$set": bson.M{"status": "used", "containerName": containerName, "timestamp": time.Now()},
-// 	}).Decode(&portDoc)
-// 	if err == mongo.ErrNoDocuments {
-// 		// No free ports in DB; optionally generate a new one
-// 		return 0, fmt.Errorf("no free ports in DB")
-// 	} else if err != nil {
-// 		return 0, err
-// 	}
-
-// 	// 3. Check if it's free on this machine
-// 	if !IsPortAvailable(portDoc.Port) {
-// 		// Update status back to "available"
-// 		_, _ = coll.UpdateOne(ctx, bson.M{"port": portDoc.Port}, bson.M{"$set": bson.M{"status": "available"}})
-// 		return GetOrReserveValidFreePort(containerName)
-// 	}
-
-// 	// 4. Open port in EC2 Security Group
-// 	if err := AuthorizeEC2Port(portDoc.Port); err != nil {
-// 		log.Printf("Failed to open port %d in SG: %v", portDoc.Port, err)
-// 		return 0, err
-// 	}
-
-// 	return portDoc.Port, nil
-// }
-
-func GetOrReserveValidFreePort(containerName string) (int, error) {
-	ctx := context.Background()
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoURI))
-	if err != nil {
-		return 0, err
-	}
-	defer client.Disconnect(ctx)
-
-	coll := client.Database(DatabaseName).Collection(CollectionName)
-
-	// Step 1: Get the latest used or available port
-	var portDoc struct {
-		Port int `bson:"port"`
-	}
-	opts := options.FindOne().SetSort(bson.D{{"port", -1}})
-	err = coll.FindOne(ctx, bson.M{}, opts).Decode(&portDoc)
-	fmt.Println("Latest port found in DB:", portDoc.Port)
-	if err == mongo.ErrNoDocuments {
-		// No ports found, start from default
-		fmt.Println("No ports found in DB, starting from default port 2000")
-		portDoc.Port = 1999 // default fallback
-	} else if err != nil {
-		return 0, fmt.Errorf("failed to find latest port: %w", err)
-	}
-	startPort := portDoc.Port + 1 // default fallback
-	// if err == nil {
-	// 	startPort = portDoc.Port+1 // start from the next port
-	// }
-
-	// Step 2: Try finding a free port by incrementing
-	for port := startPort; port <= 65535; port++ {
-		if IsPortAvailable(port) {
-			// Try reserving in DB (ensure atomicity in multi-user environments)
-			_, err := coll.UpdateOne(ctx,
-				bson.M{"port": port, "status": bson.M{"$ne": "used"}}, // <--- important
-				bson.M{
-					"$setOnInsert": bson.M{
-						"port":          port,
-						"status":        "used",
-						"containerName": containerName,
-						"timestamp":     time.Now(),
-					},
-				},
-				options.Update().SetUpsert(true),
-			)
-			if err == nil {
-				// Open the port in the active cloud provider's firewall.
-				if err := cloud.Get().AuthorizePort(port); err != nil {
-					log.Printf("Failed to open port %d in firewall: %v", port, err)
-					continue
-				}
-				return port, nil
-			}
-		}
-	}
-
-	return 0, fmt.Errorf("no free ports found")
-}
-
+// IsPortAvailable reports whether port can be bound on this host.
 func IsPortAvailable(port int) bool {
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
@@ -159,8 +35,6 @@ func tryDefaultPorts(containerID string) (int, error) {
 
 // detectPortWithNetstat uses netstat inside the container to find open ports.
 func detectPortWithNetstat(containerID string) (int, error) {
-	fmt.Println("Running netstat inside the container to detect open ports... ", containerID)
-
 	// Sleep for few seconds to ensure the container is fully up
 	time.Sleep(5 * time.Second) // <-- wait for the container to be fully up
 
@@ -171,8 +45,8 @@ func detectPortWithNetstat(containerID string) (int, error) {
 	}
 	// output, err := cmd.Output()
 	// fmt.Println("Inspecting line:", line)
-	output, err := cmd.CombinedOutput()              // <-- not just Output()
-	fmt.Println("Netstat Output:\n", string(output)) // <-- helpful to print it
+	output, err := cmd.CombinedOutput() // <-- not just Output()
+	slog.Debug("netstat output", "container", containerID, "output", string(output))
 	if err != nil {
 		return 0, fmt.Errorf("failed to exec netstat: %w", err)
 	}
@@ -183,7 +57,6 @@ func detectPortWithNetstat(containerID string) (int, error) {
 		if line == "" {
 			continue
 		}
-		fmt.Println("Inspecting line:", line) // <-- print each line for debugging
 		// Example line: "tcp        0      0 0.0.0.0:8080"
 		fields := strings.Fields(line)
 		if len(fields) >= 4 && (strings.HasPrefix(fields[0], "tcp") || strings.HasPrefix(fields[0], "udp")) {
@@ -201,12 +74,10 @@ func detectPortWithNetstat(containerID string) (int, error) {
 
 func DetectExposedPort(containerID string) (int, error) {
 	// Try default common ports first
-	fmt.Println("Detecting Exposed Port using default ports... ", containerID)
 	if port, err := tryDefaultPorts(containerID); err == nil {
 		return port, nil
 	}
-	fmt.Println("No default port matched, trying dynamic detection...")
-	fmt.Println("Detecting port using netstat inside the container... ", containerID)
+	slog.Debug("no default port matched, falling back to netstat", "container", containerID)
 	// Fallback to dynamic detection using netstat
 	return detectPortWithNetstat(containerID)
 }
@@ -0,0 +1,187 @@
+// internal/utils/signing_keys.go
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// signingKey is one entry of the JWT key set. Keys loaded from a public-key
+// file (a retired key kept around until its tokens expire) have no private half
+// and can only verify.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// keySet holds every key accepted for verification, indexed by kid, and the
+// key used to sign new tokens.
+type keySet struct {
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+var jwtKeys *keySet
+
+// loadKeySet reads the keys in JWT_KEYS_DIR. Each file is named <kid>.pem and
+// holds a PKCS#8 RSA or Ed25519 private key, or a PKIX public key for a
+// verify-only key. JWT_ACTIVE_KID picks the signing key; it defaults to the
+// last private key in lexical order so date-prefixed kids rotate naturally.
+//
+// With no JWT_KEYS_DIR an ephemeral Ed25519 key is generated, which is fine for
+// local development but invalidates every token on restart.
+func loadKeySet() (*keySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		return ephemeralKeySet()
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing JWT_KEYS_DIR: %w", err)
+	}
+	sort.Strings(paths)
+
+	ks := &keySet{keys: make(map[string]*signingKey)}
+	var lastPrivate *signingKey
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := readSigningKey(path, kid)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[kid] = key
+		if key.private != nil {
+			lastPrivate = key
+		}
+	}
+
+	if kid := os.Getenv("JWT_ACTIVE_KID"); kid != "" {
+		key, ok := ks.keys[kid]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID %q has no private key in %s", kid, dir)
+		}
+		ks.active = key
+	} else {
+		ks.active = lastPrivate
+	}
+	if ks.active == nil {
+		return nil, fmt.Errorf("no private signing key found in %s", dir)
+	}
+	return ks, nil
+}
+
+func ephemeralKeySet() (*keySet, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating ephemeral signing key: %w", err)
+	}
+	key := &signingKey{kid: "dev-" + GenerateRandomID(), method: jwt.SigningMethodEdDSA, private: priv, public: pub}
+	slog.Warn("JWT_KEYS_DIR not set, signing tokens with an ephemeral key; tokens will not survive a restart", "kid", key.kid)
+	return &keySet{keys: map[string]*signingKey{key.kid: key}, active: key}, nil
+}
+
+func readSigningKey(path, kid string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signing key %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM encoded", path)
+	}
+
+	key := &signingKey{kid: kid}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported private key type %T", path, parsed)
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case "PUBLIC KEY":
+		key.public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q (want PKCS#8 PRIVATE KEY or PUBLIC KEY)", path, block.Type)
+	}
+
+	switch key.public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T (want RSA or Ed25519)", path, key.public)
+	}
+	return key, nil
+}
+
+// JWK is a single public key in a JSON Web Key Set.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// PublicJWKS returns the public half of every verification key, for
+// /.well-known/jwks.json.
+func PublicJWKS() []JWK {
+	if jwtKeys == nil {
+		return []JWK{}
+	}
+	kids := make([]string, 0, len(jwtKeys.keys))
+	for kid := range jwtKeys.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	enc := base64.RawURLEncoding
+	jwks := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		key := jwtKeys.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = enc.EncodeToString(pub.N.Bytes())
+			jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = enc.EncodeToString(pub)
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
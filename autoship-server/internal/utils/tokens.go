@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateOpaqueToken returns a URL-safe random token with 256 bits of
+// entropy, used for refresh tokens and other bearer secrets that are stored
+// only as a hash.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 of token. Opaque tokens are high-entropy,
+// so a fast unsalted hash is enough and keeps lookups indexable.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APITokenPrefix marks personal API tokens so they can be told apart from
+// JWTs (and spotted by secret scanners).
+const APITokenPrefix = "asp_"
+
+// GenerateAPIToken returns a new personal API token value.
+func GenerateAPIToken() (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return APITokenPrefix + token, nil
+}